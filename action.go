@@ -6,6 +6,7 @@ import (
 
 	colorful "github.com/lucasb-eyer/go-colorful"
 	"github.com/ngerakines/auroraops/client"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -14,6 +15,13 @@ type Action interface {
 	Stop(ctx context.Context) error
 }
 
+// ValueAction is an action driven by a numeric status value. Its value can be
+// changed without restarting it.
+type ValueAction interface {
+	Action
+	SetValue(value float64) error
+}
+
 type noOpAction struct {
 }
 
@@ -21,24 +29,23 @@ type solidFillAction struct {
 	panels []int
 	color  colorful.Color
 
-	auroraClient client.AuroraClient
+	canvas Canvas
 }
 
 func NewNoOpAction() Action {
 	return &noOpAction{}
 }
 
-func NewSolidFillAction(auroraClient client.AuroraClient, panels []int, color colorful.Color) (Action, error) {
+func NewSolidFillAction(canvas Canvas, panels []int, color colorful.Color) (Action, error) {
 	if !color.IsValid() {
 		return nil, fmt.Errorf("error: invalid color")
 	}
-	return &solidFillAction{panels, color, auroraClient}, nil
+	return &solidFillAction{panels, color, canvas}, nil
 }
 
 func (a *solidFillAction) Start() error {
-	r, g, b := a.color.Clamped().RGB255()
 	for _, panel := range a.panels {
-		a.auroraClient.SetPanelColor(byte(panel), byte(r), byte(g), byte(b))
+		a.canvas.SetPanelColor(panel, a.color)
 	}
 	return nil
 }
@@ -71,6 +78,7 @@ func ClearPanels(auroraClient client.AuroraClient, onstart string) error {
 		return err
 	}
 
+	commands := make([]*client.PanelColorCommand, 0, len(panelInfo.Panels))
 	for _, panel := range panelInfo.Panels {
 		log.WithFields(log.Fields{
 			"panel": panel.ID,
@@ -79,8 +87,46 @@ func ClearPanels(auroraClient client.AuroraClient, onstart string) error {
 			"g":     g,
 			"b":     b,
 		}).Debug("Setting panel colors")
-		auroraClient.SetPanelColor(byte(panel.ID), byte(r), byte(g), byte(b))
+		commands = append(commands, &client.PanelColorCommand{ID: panel.ID, R: r, G: g, B: b})
 	}
 
-	return nil
+	return auroraClient.SetPanelColors(commands)
+}
+
+// colorOrDefault parses a hex color, or returns the fallback when it is unset.
+func colorOrDefault(hex string, fallback colorful.Color) (colorful.Color, error) {
+	if hex == "" {
+		return fallback, nil
+	}
+	color, err := colorful.Hex(hex)
+	if err != nil {
+		return color, errors.Wrapf(err, "invalid color: %s", hex)
+	}
+	return color, nil
+}
+
+type solidFillConfig struct {
+	Color string `mapstructure:"color" schema:"color"`
+}
+
+func init() {
+	RegisterAction("solid", newSolidFillFromConfig, solidFillConfig{})
+	RegisterAction("clear", newNoOpFromConfig, struct{}{})
+}
+
+func newSolidFillFromConfig(config map[string]interface{}, ac ActionContext) (Action, error) {
+	sc := solidFillConfig{}
+	if err := ac.DecodeConfig(config, &sc); err != nil {
+		return nil, err
+	}
+	color, err := colorful.Hex(sc.Color)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid color: %s", sc.Color)
+	}
+	return NewSolidFillAction(ac.Canvas, ac.Panels, color)
+}
+
+// newNoOpFromConfig draws nothing, letting the layers below show through.
+func newNoOpFromConfig(config map[string]interface{}, ac ActionContext) (Action, error) {
+	return NewNoOpAction(), nil
 }
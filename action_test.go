@@ -0,0 +1,101 @@
+package auroraops
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	colorful "github.com/lucasb-eyer/go-colorful"
+)
+
+// recordingCanvas keeps the last color drawn on each panel. Cleared panels are
+// removed.
+type recordingCanvas map[int]colorful.Color
+
+func (c recordingCanvas) SetPanelColor(panel int, color colorful.Color) {
+	c[panel] = color
+}
+
+func (c recordingCanvas) ClearPanel(panel int) {
+	delete(c, panel)
+}
+
+// hexes returns the colors of the canvas as hex strings.
+func (c recordingCanvas) hexes() map[int]string {
+	hexes := make(map[int]string, len(c))
+	for panel, color := range c {
+		hexes[panel] = color.Hex()
+	}
+	return hexes
+}
+
+// TestSeededActions steps random actions given a seed by hand, and expects the
+// same colors every time.
+func TestSeededActions(t *testing.T) {
+	tests := []struct {
+		name       string
+		actionType string
+		config     map[string]interface{}
+		steps      int
+		want       map[int]string
+	}{
+		{
+			name:       "twinkle",
+			actionType: "twinkle",
+			config:     map[string]interface{}{"color": "#ffffff", "density": 2, "period": "500ms", "seed": 7},
+			steps:      10,
+			want:       map[int]string{1: "#c9c9c9", 2: "#000000", 3: "#000000", 4: "#c9c9c9"},
+		},
+		{
+			name:       "twinkle from a color",
+			actionType: "twinkle",
+			config:     map[string]interface{}{"color": "#ffff00", "from": "#0000ff", "density": 1, "seed": 42},
+			steps:      20,
+			want:       map[int]string{1: "#ff0095", 2: "#0000ff", 3: "#0000ff", 4: "#ffef00"},
+		},
+		{
+			name:       "fire",
+			actionType: "fire",
+			config:     map[string]interface{}{"seed": 7},
+			steps:      10,
+			want:       map[int]string{1: "#ffc300", 2: "#c32404", 3: "#ffa700", 4: "#ff8900"},
+		},
+		{
+			name:       "fire with stops",
+			actionType: "fire",
+			config: map[string]interface{}{
+				"stops": []interface{}{
+					map[string]interface{}{"value": 0, "color": "#000000"},
+					map[string]interface{}{"value": 1, "color": "#ff0000"},
+				},
+				"period": "1s",
+				"seed":   42,
+			},
+			steps: 20,
+			want:  map[int]string{1: "#830327", 2: "#8b0027", 3: "#cf0020", 4: "#9d0027"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			canvas := recordingCanvas{}
+			action, err := newAction(StatusConfigSet{Type: tt.actionType, Config: tt.config}, ActionContext{
+				Panels: []int{1, 2, 3, 4},
+				Canvas: canvas,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			stepper, ok := action.(interface{ step(time.Duration) })
+			if !ok {
+				t.Fatalf("%T cannot be stepped", action)
+			}
+			for i := 0; i < tt.steps; i++ {
+				stepper.step(50 * time.Millisecond)
+			}
+			got := canvas.hexes()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("colors = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package auroraops
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	colorful "github.com/lucasb-eyer/go-colorful"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	tomb "gopkg.in/tomb.v2"
+)
+
+type blinkAction struct {
+	panels []int
+	on     colorful.Color
+	off    colorful.Color
+	onFor  time.Duration
+	offFor time.Duration
+	count  int
+
+	canvas Canvas
+
+	t  tomb.Tomb
+	mu sync.Mutex
+}
+
+// NewBlinkAction creates an action that switches panels between two colors
+// frequency times a second, spending duty of each cycle on. When count is
+// greater than zero, the panels hold the on color after that many blinks.
+func NewBlinkAction(canvas Canvas, panels []int, on, off colorful.Color, frequency, duty float64, count int) (Action, error) {
+	log.Info("New blink action")
+	if !on.IsValid() || !off.IsValid() {
+		return nil, fmt.Errorf("error: invalid color")
+	}
+	if frequency <= 0 {
+		return nil, fmt.Errorf("error: frequency must be greater than zero")
+	}
+	if duty <= 0 || duty >= 1 {
+		return nil, fmt.Errorf("error: duty cycle must be between 0 and 1")
+	}
+	period := time.Duration(float64(time.Second) / frequency)
+	onFor := time.Duration(float64(period) * duty)
+	return &blinkAction{
+		panels: panels,
+		on:     on,
+		off:    off,
+		onFor:  onFor,
+		offFor: period - onFor,
+		count:  count,
+		canvas: canvas,
+	}, nil
+}
+
+func (a *blinkAction) fill(color colorful.Color) {
+	for _, panel := range a.panels {
+		a.canvas.SetPanelColor(panel, color)
+	}
+}
+
+// at returns the color of the panels once elapsed has passed, how long they
+// keep it, and whether they hold it for good after the last blink.
+func (a *blinkAction) at(elapsed time.Duration) (colorful.Color, time.Duration, bool) {
+	period := a.onFor + a.offFor
+	if a.count > 0 && elapsed >= period*time.Duration(a.count) {
+		return a.on, 0, true
+	}
+	phase := elapsed % period
+	if phase < a.onFor {
+		return a.on, a.onFor - phase, false
+	}
+	return a.off, period - phase, false
+}
+
+func (a *blinkAction) loop() error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	start := time.Now()
+	for {
+		select {
+		case <-timer.C:
+			color, next, held := a.at(time.Since(start))
+			a.fill(color)
+			if held {
+				log.WithFields(log.Fields{
+					"action": "blink",
+					"blinks": a.count,
+				}).Debug("Holding")
+				return nil
+			}
+			timer.Reset(next)
+		case <-a.t.Dying():
+			return nil
+		}
+	}
+}
+
+func (a *blinkAction) Start() error {
+	a.t.Go(a.loop)
+	return nil
+}
+
+func (a *blinkAction) Stop(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	log.WithField("action", "blink").Info("Stopping")
+	a.t.Kill(nil)
+	return a.t.Wait()
+}
+
+// blinkConfig defaults to blinking to black twice a second, forever.
+type blinkConfig struct {
+	Color     string  `mapstructure:"color" schema:"color"`
+	Off       string  `mapstructure:"off" schema:"color"`
+	Frequency float64 `mapstructure:"frequency"`
+	Duty      float64 `mapstructure:"duty"`
+	Count     int     `mapstructure:"count"`
+}
+
+func init() {
+	RegisterAction("blink", newBlinkFromConfig, blinkConfig{})
+}
+
+func newBlinkFromConfig(config map[string]interface{}, ac ActionContext) (Action, error) {
+	bc := blinkConfig{}
+	if err := ac.DecodeConfig(config, &bc); err != nil {
+		return nil, err
+	}
+	on, err := colorful.Hex(bc.Color)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid color: %s", bc.Color)
+	}
+	off, err := colorOrDefault(bc.Off, colorful.Color{})
+	if err != nil {
+		return nil, err
+	}
+	frequency := bc.Frequency
+	if frequency == 0 {
+		frequency = 2
+	}
+	duty := bc.Duty
+	if duty == 0 {
+		duty = 0.5
+	}
+	return NewBlinkAction(ac.Canvas, ac.Panels, on, off, frequency, duty, bc.Count)
+}
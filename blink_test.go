@@ -0,0 +1,108 @@
+package auroraops
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBlinkAt(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   map[string]interface{}
+		elapsed  time.Duration
+		want     string
+		wantNext time.Duration
+		wantHeld bool
+	}{
+		{
+			name:     "starts on",
+			config:   map[string]interface{}{"color": "#ff0000"},
+			elapsed:  0,
+			want:     "#ff0000",
+			wantNext: 250 * time.Millisecond,
+		},
+		{
+			name:     "turns off halfway through each blink by default",
+			config:   map[string]interface{}{"color": "#ff0000"},
+			elapsed:  300 * time.Millisecond,
+			want:     "#000000",
+			wantNext: 200 * time.Millisecond,
+		},
+		{
+			name:     "turns back on every period",
+			config:   map[string]interface{}{"color": "#ff0000"},
+			elapsed:  1100 * time.Millisecond,
+			want:     "#ff0000",
+			wantNext: 150 * time.Millisecond,
+		},
+		{
+			name:     "stays on for its duty cycle",
+			config:   map[string]interface{}{"color": "#ff0000", "frequency": 1, "duty": 0.2},
+			elapsed:  150 * time.Millisecond,
+			want:     "#ff0000",
+			wantNext: 50 * time.Millisecond,
+		},
+		{
+			name:     "stays off for the rest of the period",
+			config:   map[string]interface{}{"color": "#ff0000", "frequency": 1, "duty": 0.2},
+			elapsed:  200 * time.Millisecond,
+			want:     "#000000",
+			wantNext: 800 * time.Millisecond,
+		},
+		{
+			name:     "turns off to its off color",
+			config:   map[string]interface{}{"color": "#ff0000", "off": "#0000ff"},
+			elapsed:  400 * time.Millisecond,
+			want:     "#0000ff",
+			wantNext: 100 * time.Millisecond,
+		},
+		{
+			name:     "blinks count times",
+			config:   map[string]interface{}{"color": "#ff0000", "count": 2},
+			elapsed:  750 * time.Millisecond,
+			want:     "#000000",
+			wantNext: 250 * time.Millisecond,
+		},
+		{
+			name:     "holds the color after count blinks",
+			config:   map[string]interface{}{"color": "#ff0000", "count": 2},
+			elapsed:  time.Second,
+			want:     "#ff0000",
+			wantHeld: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, err := newAction(StatusConfigSet{Type: "blink", Config: tt.config}, ActionContext{
+				Panels: []int{1},
+				Canvas: recordingCanvas{},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			color, next, held := action.(*blinkAction).at(tt.elapsed)
+			if color.Hex() != tt.want || next != tt.wantNext || held != tt.wantHeld {
+				t.Errorf("at(%v) = %s, %v, %v, want %s, %v, %v", tt.elapsed, color.Hex(), next, held, tt.want, tt.wantNext, tt.wantHeld)
+			}
+		})
+	}
+}
+
+func TestBlinkErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+	}{
+		{name: "missing color", config: map[string]interface{}{}},
+		{name: "invalid off color", config: map[string]interface{}{"color": "#ff0000", "off": "dark"}},
+		{name: "negative frequency", config: map[string]interface{}{"color": "#ff0000", "frequency": -1}},
+		{name: "duty cycle of one", config: map[string]interface{}{"color": "#ff0000", "duty": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newAction(StatusConfigSet{Type: "blink", Config: tt.config}, ActionContext{Canvas: recordingCanvas{}}); err == nil {
+				t.Errorf("newAction(%v) succeeded, want an error", tt.config)
+			}
+		})
+	}
+}
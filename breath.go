@@ -2,11 +2,13 @@ package auroraops
 
 import (
 	"context"
+	"fmt"
+	"math"
 	"sync"
 	"time"
 
 	colorful "github.com/lucasb-eyer/go-colorful"
-	"github.com/ngerakines/auroraops/client"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	tomb "gopkg.in/tomb.v2"
 )
@@ -14,9 +16,10 @@ import (
 type breathAction struct {
 	panels   []int
 	colors   []colorful.Color
+	offsets  []int
 	position int
 
-	auroraClient client.AuroraClient
+	canvas Canvas
 
 	t  tomb.Tomb
 	mu sync.Mutex
@@ -27,9 +30,18 @@ type gradientTable []struct {
 	Pos float64
 }
 
-func NewBreathAction(auroraClient client.AuroraClient, panels []int, to, from colorful.Color, seconds int) (Action, error) {
+// NewBreathAction creates an action that breathes from one color to another
+// and back over the period. Each panel is offset from the previous one by
+// phase, a fraction of the period.
+func NewBreathAction(canvas Canvas, panels []int, to, from colorful.Color, period time.Duration, easing Easing, blend Blend, phase float64) (Action, error) {
 	log.Info("New breath action")
-	steps := seconds * 20
+	if !to.IsValid() || !from.IsValid() {
+		return nil, fmt.Errorf("error: invalid color")
+	}
+	steps := int(period / (50 * time.Millisecond))
+	if steps < 1 {
+		steps = 1
+	}
 	keypoints := gradientTable{
 		{from, 0.0},
 		{to, 0.2},
@@ -38,14 +50,22 @@ func NewBreathAction(auroraClient client.AuroraClient, panels []int, to, from co
 	}
 	colors := []colorful.Color{}
 	for y := steps; y >= 0; y-- {
-		c := keypoints.getInterpolatedColorFor(float64(y) / float64(steps))
+		c := keypoints.interpolate(float64(y)/float64(steps), easing, blend)
 		colors = append(colors, c)
 	}
+	offsets := make([]int, len(panels))
+	for i := range panels {
+		offsets[i] = int(math.Round(phase*float64(i)*float64(steps))) % len(colors)
+		if offsets[i] < 0 {
+			offsets[i] += len(colors)
+		}
+	}
 	ba := &breathAction{
-		panels:       panels,
-		colors:       colors,
-		position:     0,
-		auroraClient: auroraClient,
+		panels:   panels,
+		colors:   colors,
+		offsets:  offsets,
+		position: 0,
+		canvas:   canvas,
 	}
 	return ba, nil
 }
@@ -58,16 +78,14 @@ func (a *breathAction) loop() error {
 			if a.position >= len(a.colors) {
 				a.position = 0
 			}
-			color := a.colors[a.position]
 			log.WithFields(log.Fields{
 				"t":        t,
 				"action":   "breath",
 				"position": a.position,
-				"color":    color.Hex(),
 			}).Debug("Tick")
-			r, g, b := color.Clamped().RGB255()
-			for _, panel := range a.panels {
-				a.auroraClient.SetPanelColor(byte(panel), byte(r), byte(g), byte(b))
+			for i, panel := range a.panels {
+				color := a.colors[(a.position+a.offsets[i])%len(a.colors)]
+				a.canvas.SetPanelColor(panel, color)
 			}
 			a.position = a.position + 1
 		case <-a.t.Dying():
@@ -91,14 +109,65 @@ func (ba *breathAction) Stop(ctx context.Context) error {
 }
 
 func (gt gradientTable) getInterpolatedColorFor(t float64) colorful.Color {
+	return gt.interpolate(t, easings["linear"], blends["hcl"])
+}
+
+// interpolate finds the keypoints around t and blends them, easing the
+// progress between the two.
+func (gt gradientTable) interpolate(t float64, easing Easing, blend Blend) colorful.Color {
 	for i := 0; i < len(gt)-1; i++ {
 		c1 := gt[i]
 		c2 := gt[i+1]
 		if c1.Pos <= t && t <= c2.Pos {
 			// We are in between c1 and c2. Go blend them!
+			if c2.Pos == c1.Pos {
+				return c2.Col
+			}
 			t := (t - c1.Pos) / (c2.Pos - c1.Pos)
-			return c1.Col.BlendHcl(c2.Col, t).Clamped()
+			return blend(c1.Col, c2.Col, easing(t))
 		}
 	}
 	return gt[len(gt)-1].Col
 }
+
+// breathConfig defaults to a one second breath from white blended in HCL.
+type breathConfig struct {
+	Color  string        `mapstructure:"color" schema:"color"`
+	From   string        `mapstructure:"from" schema:"color"`
+	Period time.Duration `mapstructure:"period"`
+	Easing string        `mapstructure:"easing"`
+	Blend  string        `mapstructure:"blend"`
+	Phase  float64       `mapstructure:"phase"`
+}
+
+func init() {
+	RegisterAction("breath", newBreathFromConfig, breathConfig{})
+}
+
+func newBreathFromConfig(config map[string]interface{}, ac ActionContext) (Action, error) {
+	bc := breathConfig{}
+	if err := ac.DecodeConfig(config, &bc); err != nil {
+		return nil, err
+	}
+	to, err := colorful.Hex(bc.Color)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid color: %s", bc.Color)
+	}
+	from, err := colorOrDefault(bc.From, colorful.Color{R: 1, G: 1, B: 1})
+	if err != nil {
+		return nil, err
+	}
+	period := bc.Period
+	if period <= 0 {
+		period = time.Second
+	}
+	easing, err := EasingFor(bc.Easing)
+	if err != nil {
+		return nil, err
+	}
+	blend, err := BlendFor(bc.Blend)
+	if err != nil {
+		return nil, err
+	}
+	return NewBreathAction(ac.Canvas, ac.Panels, to, from, period, easing, blend, bc.Phase)
+}
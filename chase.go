@@ -0,0 +1,152 @@
+package auroraops
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	colorful "github.com/lucasb-eyer/go-colorful"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	tomb "gopkg.in/tomb.v2"
+)
+
+type chaseAction struct {
+	panels []int
+	from   colorful.Color
+	to     colorful.Color
+	length int
+	period time.Duration
+	blend  Blend
+
+	canvas Canvas
+
+	t  tomb.Tomb
+	mu sync.Mutex
+}
+
+// NewChaseAction creates an action that moves a segment of length panels lit
+// with a color through the panels, in the order given, once per period. The
+// segment moves smoothly, partially lighting the panels at either end.
+func NewChaseAction(canvas Canvas, panels []int, from, to colorful.Color, length int, period time.Duration, blend Blend) (Action, error) {
+	log.Info("New chase action")
+	if !from.IsValid() || !to.IsValid() {
+		return nil, fmt.Errorf("error: invalid color")
+	}
+	if period <= 0 {
+		return nil, fmt.Errorf("error: period must be greater than zero")
+	}
+	if length < 1 {
+		return nil, fmt.Errorf("error: length must be at least one")
+	}
+	return &chaseAction{
+		panels: panels,
+		from:   from,
+		to:     to,
+		length: length,
+		period: period,
+		blend:  blend,
+		canvas: canvas,
+	}, nil
+}
+
+func (a *chaseAction) draw(elapsed time.Duration) {
+	n := float64(len(a.panels))
+	head := n * float64(elapsed%a.period) / float64(a.period)
+	for i, panel := range a.panels {
+		// How far behind the head of the segment the panel is, wrapping
+		// around the end of the panels.
+		behind := math.Mod(head-float64(i)+n, n)
+		lit := clamp(math.Min(behind, float64(a.length)-behind+1))
+		a.canvas.SetPanelColor(panel, a.blend(a.from, a.to, lit))
+	}
+}
+
+func (a *chaseAction) loop() error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	start := time.Now()
+	a.draw(0)
+	for {
+		select {
+		case t := <-ticker.C:
+			a.draw(t.Sub(start))
+		case <-a.t.Dying():
+			return nil
+		}
+	}
+}
+
+func (a *chaseAction) Start() error {
+	a.t.Go(a.loop)
+	return nil
+}
+
+func (a *chaseAction) Stop(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	log.WithField("action", "chase").Info("Stopping")
+	a.t.Kill(nil)
+	return a.t.Wait()
+}
+
+// chaseConfig defaults to one panel travelling through the panels in the
+// order they are listed over two seconds. Order can also be "reverse" or
+// "geometry", which follows Direction across the wall.
+type chaseConfig struct {
+	Color     string        `mapstructure:"color" schema:"color"`
+	From      string        `mapstructure:"from" schema:"color"`
+	Period    time.Duration `mapstructure:"period"`
+	Length    int           `mapstructure:"length"`
+	Order     string        `mapstructure:"order"`
+	Direction float64       `mapstructure:"direction"`
+	Blend     string        `mapstructure:"blend"`
+}
+
+func init() {
+	RegisterAction("chase", newChaseFromConfig, chaseConfig{})
+}
+
+func newChaseFromConfig(config map[string]interface{}, ac ActionContext) (Action, error) {
+	cc := chaseConfig{}
+	if err := ac.DecodeConfig(config, &cc); err != nil {
+		return nil, err
+	}
+	to, err := colorful.Hex(cc.Color)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid color: %s", cc.Color)
+	}
+	from, err := colorOrDefault(cc.From, colorful.Color{})
+	if err != nil {
+		return nil, err
+	}
+	period := cc.Period
+	if period <= 0 {
+		period = 2 * time.Second
+	}
+	length := cc.Length
+	if length == 0 {
+		length = 1
+	}
+	blend, err := BlendFor(cc.Blend)
+	if err != nil {
+		return nil, err
+	}
+	ordered := ac.Panels
+	switch cc.Order {
+	case "", "list":
+	case "reverse":
+		ordered = make([]int, len(ac.Panels))
+		for i, panel := range ac.Panels {
+			ordered[len(ac.Panels)-1-i] = panel
+		}
+	case "geometry":
+		ordered = inDirection(ac.Geometry, cc.Direction)
+	default:
+		return nil, fmt.Errorf("unsupported order: %s", cc.Order)
+	}
+	return NewChaseAction(ac.Canvas, ordered, from, to, length, period, blend)
+}
@@ -0,0 +1,99 @@
+package auroraops
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ngerakines/auroraops/client"
+)
+
+func TestChaseAction(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		elapsed time.Duration
+		want    map[int]string
+	}{
+		{
+			name:    "lights one panel at a time",
+			config:  map[string]interface{}{"color": "#ffffff", "period": "4s", "blend": "rgb"},
+			elapsed: time.Second,
+			want:    map[int]string{1: "#ffffff", 2: "#000000", 3: "#000000", 4: "#000000"},
+		},
+		{
+			name:    "moves to the next panel",
+			config:  map[string]interface{}{"color": "#ffffff", "period": "4s", "blend": "rgb"},
+			elapsed: 2 * time.Second,
+			want:    map[int]string{1: "#000000", 2: "#ffffff", 3: "#000000", 4: "#000000"},
+		},
+		{
+			name:    "moves smoothly between panels",
+			config:  map[string]interface{}{"color": "#ffffff", "period": "4s", "blend": "rgb"},
+			elapsed: 1500 * time.Millisecond,
+			want:    map[int]string{1: "#808080", 2: "#808080", 3: "#000000", 4: "#000000"},
+		},
+		{
+			name:    "wraps around the end of the panels",
+			config:  map[string]interface{}{"color": "#ffffff", "period": "4s", "blend": "rgb"},
+			elapsed: 4 * time.Second,
+			want:    map[int]string{1: "#000000", 2: "#000000", 3: "#000000", 4: "#ffffff"},
+		},
+		{
+			name:    "lights length panels",
+			config:  map[string]interface{}{"color": "#ffffff", "from": "#0000ff", "period": "4s", "length": 2, "blend": "rgb"},
+			elapsed: 2 * time.Second,
+			want:    map[int]string{1: "#ffffff", 2: "#ffffff", 3: "#0000ff", 4: "#0000ff"},
+		},
+		{
+			name:    "runs in reverse",
+			config:  map[string]interface{}{"color": "#ffffff", "period": "4s", "order": "reverse", "blend": "rgb"},
+			elapsed: time.Second,
+			want:    map[int]string{1: "#000000", 2: "#000000", 3: "#000000", 4: "#ffffff"},
+		},
+		{
+			name:    "runs across the wall",
+			config:  map[string]interface{}{"color": "#ffffff", "period": "4s", "order": "geometry", "direction": 180, "blend": "rgb"},
+			elapsed: time.Second,
+			want:    map[int]string{1: "#000000", 2: "#ffffff", 3: "#000000", 4: "#000000"},
+		},
+	}
+	// The panels are listed in a different order than they are on the wall,
+	// from left to right 3, 1, 4, 2.
+	geometry := []*client.Panel{{ID: 1, X: 100}, {ID: 2, X: 300}, {ID: 3, X: 0}, {ID: 4, X: 200}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			canvas := recordingCanvas{}
+			action, err := newAction(StatusConfigSet{Type: "chase", Config: tt.config}, ActionContext{
+				Panels:   []int{1, 2, 3, 4},
+				Geometry: geometry,
+				Canvas:   canvas,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			action.(*chaseAction).draw(tt.elapsed)
+			if got := canvas.hexes(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("colors = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChaseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+	}{
+		{name: "missing color", config: map[string]interface{}{}},
+		{name: "negative length", config: map[string]interface{}{"color": "#ffffff", "length": -1}},
+		{name: "unknown order", config: map[string]interface{}{"color": "#ffffff", "order": "random"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newAction(StatusConfigSet{Type: "chase", Config: tt.config}, ActionContext{Canvas: recordingCanvas{}}); err == nil {
+				t.Errorf("newAction(%v) succeeded, want an error", tt.config)
+			}
+		})
+	}
+}
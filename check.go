@@ -0,0 +1,382 @@
+package auroraops
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	colorful "github.com/lucasb-eyer/go-colorful"
+	"github.com/ngerakines/auroraops/client"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Diagnostic is a problem found in the configuration. Path is the keys that
+// lead to the offending value, such as ["things", "website", "panels", "2"].
+// File, Line and Column are set once the diagnostic is located.
+type Diagnostic struct {
+	Severity string
+	Path     []string
+	Message  string
+
+	File   string
+	Line   int
+	Column int
+}
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+func (d Diagnostic) String() string {
+	location := ""
+	if d.File != "" {
+		location = d.File + ":"
+		if d.Line > 0 {
+			location = fmt.Sprintf("%s%d:%d:", location, d.Line, d.Column)
+		}
+		location = location + " "
+	}
+	return fmt.Sprintf("%s%s: %s: %s", location, d.Severity, strings.Join(d.Path, "."), d.Message)
+}
+
+// ConfigCheck is the configuration checked by CheckConfig. Devices, Status,
+// Things, Regions and Schedules are the raw sections of the configuration.
+// Layout is the stitched layout of every device; without it, panels are not
+// checked against the auroras and selectors are not resolved. Statuses are
+// referenced by the value of things, and by the remote statuses in Remote.
+type ConfigCheck struct {
+	Devices   interface{}
+	Status    interface{}
+	Things    interface{}
+	Regions   interface{}
+	Schedules interface{}
+	OnStart   string
+	OnStop    string
+	Layout    []*client.Panel
+	Remote    StatusMap
+}
+
+// nullCanvas is drawn on by actions that are only created to be checked.
+type nullCanvas struct{}
+
+func (nullCanvas) SetPanelColor(panel int, color colorful.Color) {}
+
+func (nullCanvas) ClearPanel(panel int) {}
+
+// CheckConfig checks every part of a configuration without running it, and
+// returns every problem found rather than stopping at the first. Each status is
+// created once for every thing, which checks colors and action parameters.
+func CheckConfig(config ConfigCheck) []Diagnostic {
+	c := &configChecker{}
+
+	c.checkColor([]string{"onstart"}, config.OnStart)
+	c.checkColor([]string{"onstop"}, config.OnStop)
+
+	devices := []DeviceConfig{}
+	if config.Devices != nil {
+		parsed, err := ParseDeviceConfig(config.Devices)
+		if err != nil {
+			c.add(SeverityError, []string{"devices"}, err.Error())
+		} else {
+			devices = parsed
+		}
+	}
+
+	layout := make(map[int]*client.Panel)
+	allPanels := []int{}
+	for _, panel := range config.Layout {
+		layout[panel.ID] = panel
+		allPanels = append(allPanels, panel.ID)
+	}
+
+	statuses := map[string]interface{}{}
+	if err := decodeConfig(config.Status, &statuses, false); err != nil {
+		c.add(SeverityError, []string{"status"}, err.Error())
+	}
+	parsed := make(map[string]StatusConfigSet)
+	for status, raw := range statuses {
+		if pollerKeys[status] {
+			continue
+		}
+		path := []string{"status", status}
+		statusConfig, err := parseStatusConfig(raw)
+		if err != nil {
+			c.add(SeverityError, path, err.Error())
+			continue
+		}
+		if err := checkStatusConfig(statusConfig, devices); err != nil {
+			c.add(SeverityError, path, err.Error())
+			continue
+		}
+		if _, err := newTransition(statusConfig.Transition, nil, nil, nil); err != nil {
+			c.add(SeverityError, append(path, "transition"), err.Error())
+		}
+		parsed[status] = statusConfig
+	}
+
+	regionConfigs := map[string]interface{}{}
+	if err := decodeConfig(config.Regions, &regionConfigs, false); err != nil {
+		c.add(SeverityError, []string{"regions"}, err.Error())
+	}
+	regions := make(map[string][]PanelSelector)
+	for region, raw := range regionConfigs {
+		path := []string{"regions", region}
+		selectors := []PanelSelector{}
+		if err := decodeConfig(raw, &selectors, true); err != nil {
+			c.add(SeverityError, path, err.Error())
+			continue
+		}
+		c.checkSelectors(path, selectors)
+		regions[strings.ToLower(region)] = selectors
+	}
+	for region, selectors := range regions {
+		if _, err := expandRegions(selectors, regions, []string{region}); err != nil {
+			c.add(SeverityError, []string{"regions", region}, err.Error())
+		}
+	}
+
+	thingConfigs := map[string]interface{}{}
+	if err := decodeConfig(config.Things, &thingConfigs, false); err != nil {
+		c.add(SeverityError, []string{"things"}, err.Error())
+	}
+	things := make(map[string]ThingConfigSet)
+	assigned := make(map[int]bool)
+	for thing, raw := range thingConfigs {
+		path := []string{"things", thing}
+		thingConfig := ThingConfigSet{}
+		if err := decodeConfig(raw, &thingConfig, true, panelRefHookFunc(devices)); err != nil {
+			c.add(SeverityError, path, err.Error())
+			continue
+		}
+		if !c.checkSelectors(append(path, "select"), thingConfig.Select) {
+			thingConfig.Select = nil
+		} else if selectors, err := expandRegions(thingConfig.Select, regions, []string{}); err != nil {
+			c.add(SeverityError, append(path, "select"), err.Error())
+			thingConfig.Select = nil
+		} else {
+			thingConfig.Select = selectors
+		}
+		things[thing] = thingConfig
+
+		if !thingConfig.All && len(thingConfig.Panels) == 0 && len(thingConfig.Select) == 0 {
+			c.add(SeverityWarning, path, "thing has no panels")
+		}
+		for i, panel := range thingConfig.Panels {
+			if config.Layout != nil && layout[panel] == nil {
+				c.add(SeverityError, append(path, "panels", strconv.Itoa(i)), fmt.Sprintf("panel %s is not on the aurora", FormatPanelRef(devices, panel)))
+			}
+		}
+		if thingConfig.Opacity < 0 || thingConfig.Opacity > 1 {
+			c.add(SeverityError, append(path, "opacity"), "opacity must be between 0 and 1")
+		}
+		if thingConfig.Value != "" {
+			if _, ok := parsed[thingConfig.Value]; !ok {
+				c.add(SeverityError, append(path, "value"), fmt.Sprintf("unknown status: %s", thingConfig.Value))
+			}
+		}
+		c.checkColor(append(path, "onstart"), thingConfig.OnStart)
+		c.checkColor(append(path, "onstop"), thingConfig.OnStop)
+	}
+	resolved := things
+	if config.Layout != nil {
+		var err error
+		if resolved, err = resolveThings(things, config.Layout); err != nil {
+			c.add(SeverityError, []string{"things"}, err.Error())
+			resolved = things
+		}
+		for thing, thingConfig := range things {
+			if len(thingConfig.Select) > 0 && len(resolved[thing].Panels) == len(thingConfig.Panels) {
+				c.add(SeverityWarning, []string{"things", thing, "select"}, "no panels are selected")
+			}
+		}
+	}
+	for _, thingConfig := range resolved {
+		for _, panel := range thingConfig.Panels {
+			assigned[panel] = true
+		}
+	}
+	if err := checkThings(resolved); err != nil {
+		c.add(SeverityError, []string{"things"}, err.Error())
+	}
+
+	targets := make(map[string][]int)
+	for thing, thingConfig := range resolved {
+		targets[thing] = thingConfig.Panels
+		if thingConfig.All {
+			targets[thing] = allPanels
+		}
+	}
+	if len(targets) == 0 {
+		targets[""] = []int{}
+	}
+	for status, statusConfig := range parsed {
+		failures := map[string]bool{}
+		for thing, panels := range targets {
+			geometry := []*client.Panel{}
+			for _, panel := range panels {
+				if p, ok := layout[panel]; ok {
+					geometry = append(geometry, p)
+				}
+			}
+			_, err := newAction(statusConfig, ActionContext{
+				Status:   status,
+				Panels:   panels,
+				Geometry: geometry,
+				Layout:   layout,
+				Devices:  devices,
+				Canvas:   nullCanvas{},
+			})
+			if err != nil && !failures[err.Error()] {
+				failures[err.Error()] = true
+				message := err.Error()
+				if thing != "" {
+					message = fmt.Sprintf("%s (for thing %s)", message, thing)
+				}
+				c.add(SeverityError, []string{"status", status}, message)
+			}
+		}
+	}
+
+	schedules := []interface{}{}
+	if err := decodeConfig(config.Schedules, &schedules, false); err != nil {
+		c.add(SeverityError, []string{"schedules"}, err.Error())
+	}
+	for i, raw := range schedules {
+		path := []string{"schedules", strconv.Itoa(i)}
+		schedule := ScheduleConfig{}
+		if err := decodeConfig(raw, &schedule, true); err != nil {
+			c.add(SeverityError, path, err.Error())
+			continue
+		}
+		if _, err := newWindow(schedule); err != nil {
+			c.add(SeverityError, path, err.Error())
+		}
+	}
+
+	if config.Layout != nil {
+		covered := false
+		for _, thingConfig := range things {
+			covered = covered || thingConfig.All
+		}
+		for _, panel := range allPanels {
+			if !covered && !assigned[panel] {
+				c.add(SeverityWarning, []string{"things"}, fmt.Sprintf("panel %s is not assigned to any thing", FormatPanelRef(devices, panel)))
+			}
+		}
+		for _, pair := range overlappingDevices(config.Layout) {
+			if pair[1] < len(devices) {
+				c.add(SeverityWarning, []string{"devices", strconv.Itoa(pair[1])}, fmt.Sprintf("panels overlap the panels of device %s, set offset to place the devices apart", devices[pair[0]].Name))
+			}
+		}
+	}
+
+	referenced := make(map[string]bool)
+	for _, thingConfig := range things {
+		referenced[thingConfig.Value] = true
+	}
+	for _, status := range config.Remote {
+		referenced[status] = true
+	}
+	for status := range parsed {
+		if !referenced[status] {
+			c.add(SeverityWarning, []string{"status", status}, "status is never referenced")
+		}
+	}
+	for thing := range config.Remote {
+		if _, ok := things[thing]; !ok {
+			c.add(SeverityWarning, []string{"things"}, fmt.Sprintf("remote thing %s is not configured", thing))
+		}
+	}
+
+	sort.SliceStable(c.diagnostics, func(i, j int) bool {
+		return strings.Join(c.diagnostics[i].Path, ".") < strings.Join(c.diagnostics[j].Path, ".")
+	})
+	return c.diagnostics
+}
+
+type configChecker struct {
+	diagnostics []Diagnostic
+}
+
+func (c *configChecker) add(severity string, path []string, message string) {
+	c.diagnostics = append(c.diagnostics, Diagnostic{
+		Severity: severity,
+		Path:     append([]string{}, path...),
+		Message:  strings.TrimPrefix(message, "error: "),
+	})
+}
+
+// checkSelectors returns false when any of the selectors is invalid.
+func (c *configChecker) checkSelectors(path []string, selectors []PanelSelector) bool {
+	valid := true
+	for i, selector := range selectors {
+		if err := checkSelector(selector); err != nil {
+			c.add(SeverityError, append(path, strconv.Itoa(i)), err.Error())
+			valid = false
+		}
+	}
+	return valid
+}
+
+func (c *configChecker) checkColor(path []string, hex string) {
+	if hex == "" {
+		return
+	}
+	if _, err := colorful.Hex(hex); err != nil {
+		c.add(SeverityError, path, fmt.Sprintf("invalid color: %s", hex))
+	}
+}
+
+// LocateDiagnostics sets the file, line and column of each diagnostic to
+// where its path is in a YAML or JSON configuration file. Keys are matched
+// regardless of case, as they are when the configuration is loaded.
+func LocateDiagnostics(diagnostics []Diagnostic, file string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return errors.Wrapf(err, "could not read %s", file)
+	}
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(data, root); err != nil {
+		return errors.Wrapf(err, "could not parse %s", file)
+	}
+	for i := range diagnostics {
+		diagnostics[i].File = file
+		diagnostics[i].Line, diagnostics[i].Column = locate(root, diagnostics[i].Path)
+	}
+	return nil
+}
+
+// locate returns the position of the deepest node along the path.
+func locate(node *yaml.Node, path []string) (int, int) {
+	line, column := 0, 0
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	for _, key := range path {
+		var next *yaml.Node
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if strings.EqualFold(node.Content[i].Value, key) {
+					line, column = node.Content[i].Line, node.Content[i].Column
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			if index, err := strconv.Atoi(key); err == nil && index >= 0 && index < len(node.Content) {
+				next = node.Content[index]
+				line, column = next.Line, next.Column
+			}
+		}
+		if next == nil {
+			break
+		}
+		node = next
+	}
+	return line, column
+}
@@ -0,0 +1,200 @@
+package auroraops
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/ngerakines/auroraops/client"
+)
+
+func TestCheckConfig(t *testing.T) {
+	status := map[string]interface{}{
+		"up": map[string]interface{}{"type": "solid", "color": "#00ff00"},
+	}
+	layout := []*client.Panel{{ID: 1}, {ID: 2, X: 100}, {ID: 3, X: 200}}
+	devices := []interface{}{
+		map[string]interface{}{"name": "hall", "url": "http://hall"},
+		map[string]interface{}{"name": "lobby", "url": "http://lobby"},
+	}
+	tests := []struct {
+		name   string
+		config ConfigCheck
+		want   []string
+	}{
+		{
+			name: "accepts a valid configuration",
+			config: ConfigCheck{
+				Status: status,
+				Things: map[string]interface{}{"website": map[string]interface{}{"value": "up", "panels": []interface{}{1, 2, 3}}},
+				Layout: layout,
+			},
+			want: []string{},
+		},
+		{
+			name: "reports invalid colors",
+			config: ConfigCheck{
+				Status:  status,
+				Things:  map[string]interface{}{"website": map[string]interface{}{"value": "up", "panels": []interface{}{1}, "onstop": "red"}},
+				OnStart: "#12",
+			},
+			want: []string{
+				"error: onstart: invalid color: #12",
+				"error: things.website.onstop: invalid color: red",
+			},
+		},
+		{
+			name: "reports statuses that cannot be created",
+			config: ConfigCheck{
+				Status: map[string]interface{}{
+					"up":      map[string]interface{}{"type": "solid", "color": "green"},
+					"unknown": map[string]interface{}{"type": "sparkle"},
+				},
+			},
+			want: []string{
+				"error: status.unknown: unsupported status type: sparkle",
+				"error: status.up: invalid color: green: input does not match format",
+				"warning: status.up: status is never referenced",
+			},
+		},
+		{
+			name: "reports panels that are not on the aurora",
+			config: ConfigCheck{
+				Status: status,
+				Things: map[string]interface{}{"website": map[string]interface{}{"value": "up", "panels": []interface{}{1, 9}}},
+				Layout: layout,
+			},
+			want: []string{
+				"warning: things: panel 2 is not assigned to any thing",
+				"warning: things: panel 3 is not assigned to any thing",
+				"error: things.website.panels.1: panel 9 is not on the aurora",
+			},
+		},
+		{
+			name: "reports things without panels and unknown values",
+			config: ConfigCheck{
+				Status: status,
+				Things: map[string]interface{}{"website": map[string]interface{}{"value": "sideways"}},
+			},
+			want: []string{
+				"warning: status.up: status is never referenced",
+				"warning: things.website: thing has no panels",
+				"error: things.website.value: unknown status: sideways",
+			},
+		},
+		{
+			name: "reports statuses no thing references",
+			config: ConfigCheck{
+				Status: map[string]interface{}{
+					"up":   map[string]interface{}{"type": "solid", "color": "#00ff00"},
+					"down": map[string]interface{}{"type": "solid", "color": "#ff0000"},
+				},
+				Things: map[string]interface{}{"website": map[string]interface{}{"value": "up", "panels": []interface{}{1}}},
+			},
+			want: []string{
+				"warning: status.down: status is never referenced",
+			},
+		},
+		{
+			name: "reports invalid schedules",
+			config: ConfigCheck{
+				Schedules: []interface{}{map[string]interface{}{"start": "22:00", "end": "7pm"}},
+			},
+			want: []string{
+				"error: schedules.0: invalid time of day: 7pm: parsing time \"7pm\" as \"15:04\": cannot parse \"pm\" as \":\"",
+			},
+		},
+		{
+			name: "reports statuses and things the remote configuration does not match",
+			config: ConfigCheck{
+				Status: map[string]interface{}{
+					"up":   map[string]interface{}{"type": "solid", "color": "#00ff00"},
+					"down": map[string]interface{}{"type": "solid", "color": "#ff0000"},
+				},
+				Things: map[string]interface{}{"website": map[string]interface{}{"panels": []interface{}{1}}},
+				Remote: StatusMap{"website": "up", "api": "up"},
+			},
+			want: []string{
+				"warning: status.down: status is never referenced",
+				"warning: things: remote thing api is not configured",
+			},
+		},
+		{
+			name: "resolves panels of named devices",
+			config: ConfigCheck{
+				Devices: devices,
+				Status:  status,
+				Things:  map[string]interface{}{"website": map[string]interface{}{"value": "up", "panels": []interface{}{1, "lobby:2"}}},
+				Layout:  []*client.Panel{{ID: 1}, {ID: devicePanelSpan + 2, X: 500}},
+			},
+			want: []string{},
+		},
+		{
+			name: "reports panels of unknown devices",
+			config: ConfigCheck{
+				Devices: devices,
+				Status:  status,
+				Things:  map[string]interface{}{"website": map[string]interface{}{"value": "up", "panels": []interface{}{1, "attic:2"}}},
+			},
+			want: []string{
+				"warning: status.up: status is never referenced",
+				"error: things.website: invalid configuration: error decoding 'panels[1]': error: unknown device: attic",
+			},
+		},
+		{
+			name: "warns about devices whose panels overlap",
+			config: ConfigCheck{
+				Devices: devices,
+				Status:  status,
+				Things:  map[string]interface{}{"website": map[string]interface{}{"value": "up", "all": true}},
+				Layout:  []*client.Panel{{ID: 1}, {ID: devicePanelSpan + 2}},
+			},
+			want: []string{
+				"warning: devices.1: panels overlap the panels of device hall, set offset to place the devices apart",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, diagnostic := range CheckConfig(tt.config) {
+				got = append(got, diagnostic.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckConfig() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocateDiagnostics(t *testing.T) {
+	file, err := ioutil.TempFile("", "auroraops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	data := "status:\n  up:\n    type: solid\nthings:\n  Website:\n    panels: [1, 9]\n"
+	if _, err := file.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	diagnostics := []Diagnostic{
+		{Severity: SeverityError, Path: []string{"things", "website", "panels", "1"}, Message: "panel 9 is not on the aurora"},
+		{Severity: SeverityError, Path: []string{"status", "up", "color"}, Message: "missing color"},
+		{Severity: SeverityWarning, Path: []string{"schedules"}, Message: "not located"},
+	}
+	if err := LocateDiagnostics(diagnostics, file.Name()); err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]int{{6, 17}, {2, 3}, {0, 0}}
+	for i, diagnostic := range diagnostics {
+		if diagnostic.File != file.Name() {
+			t.Errorf("diagnostic %d file = %s, want %s", i, diagnostic.File, file.Name())
+		}
+		if got := [2]int{diagnostic.Line, diagnostic.Column}; got != want[i] {
+			t.Errorf("diagnostic %d position = %v, want %v", i, got, want[i])
+		}
+	}
+}
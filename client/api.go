@@ -1,6 +1,7 @@
 package client
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -19,12 +20,17 @@ var (
 	authURL    = "/new"
 	infoURL    = "/%s"
 	effectsURL = "/%s/effects"
+	stateURL   = "/%s/state"
 )
 
 type AuroraClient interface {
 	Authorize() (string, error)
 	GetInfo() (*HardwareInfo, error)
 	SetPanelColor(panel, r, g, b byte) error
+	SetPanelColors(commands []*PanelColorCommand) error
+	SetPower(on bool) error
+	SetBrightness(brightness int) error
+	TouchEvents(stop <-chan struct{}) (<-chan int, error)
 	Stop() error
 }
 
@@ -57,6 +63,18 @@ func NewWithToken(address, token string) (AuroraClient, error) {
 	return client, nil
 }
 
+// NewReadOnly creates a client with a provided token that does not take over
+// the panels. It can read from and configure the device, but cannot set panel
+// colors.
+func NewReadOnly(address, token string) (AuroraClient, error) {
+	var ecLock sync.Mutex
+	return &auroraClient{
+		address: address,
+		token:   token,
+		ecLock:  &ecLock,
+	}, nil
+}
+
 // Authorize will attempt to get an auth token from the device. Device must be put into pairing mode (hold down power button 5-7 seconds) for this to work.
 // On success will return a valid auth token.
 func (c *auroraClient) Authorize() (string, error) {
@@ -76,14 +94,38 @@ func (c *auroraClient) GetInfo() (*HardwareInfo, error) {
 	if err := c.request("GET", c.url(infoURL), nil, dat); err != nil {
 		return nil, err
 	}
+	if err := dat.parseLayout(); err != nil {
+		return nil, err
+	}
+	return dat, nil
+}
+
+// ParseHardwareInfo reads hardware information saved from a nanoleaf aurora,
+// such as a layout file used when the device is not reachable.
+func ParseHardwareInfo(data []byte) (*HardwareInfo, error) {
+	dat := &HardwareInfo{}
+	if err := json.Unmarshal(data, dat); err != nil {
+		return nil, errors.Wrap(err, "invalid hardware information")
+	}
+	if len(dat.Panels) > 0 {
+		return dat, nil
+	}
+	if err := dat.parseLayout(); err != nil {
+		return nil, err
+	}
+	return dat, nil
+}
+
+// parseLayout fills in the panels from the layout data of the device.
+func (dat *HardwareInfo) parseLayout() error {
 	parts := strings.Split(dat.PanelLayout.Layout.LayoutData, " ")
 	if len(parts) <= 2 {
-		return dat, nil
+		return nil
 	}
 	n, _ := strconv.Atoi(parts[0])
 	side, _ := strconv.Atoi(parts[1])
 	if len(parts) < (n*4)+2 {
-		return nil, fmt.Errorf("Invalid panel layout data")
+		return fmt.Errorf("Invalid panel layout data")
 	}
 	for i := 0; i < n; i++ {
 		p := &Panel{
@@ -95,7 +137,7 @@ func (c *auroraClient) GetInfo() (*HardwareInfo, error) {
 		p.Rotation, _ = strconv.Atoi(parts[5+i*4])
 		dat.Panels = append(dat.Panels, p)
 	}
-	return dat, nil
+	return nil
 }
 
 func (c *auroraClient) initExternalCommands() error {
@@ -132,6 +174,43 @@ func (c *auroraClient) SetPanelColor(panel, r, g, b byte) error {
 	return c.ec.Execute(panel, r, g, b)
 }
 
+// SetPanelColors sends a frame of panel colors to the device at once.
+func (c *auroraClient) SetPanelColors(commands []*PanelColorCommand) error {
+	c.ecLock.Lock()
+	defer c.ecLock.Unlock()
+
+	if c.ec == nil {
+		return fmt.Errorf("error: external command is not set")
+	}
+
+	return c.ec.ExecuteFrame(commands)
+}
+
+// SetPower turns the nanoleaf aurora on or off.
+func (c *auroraClient) SetPower(on bool) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"on": map[string]bool{"value": on},
+	})
+	if err != nil {
+		return err
+	}
+	return c.request("PUT", c.url(stateURL), bytes.NewReader(body), nil)
+}
+
+// SetBrightness sets the brightness of the nanoleaf aurora, from 0 to 100.
+func (c *auroraClient) SetBrightness(brightness int) error {
+	if brightness < 0 || brightness > 100 {
+		return fmt.Errorf("error: brightness must be between 0 and 100")
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"brightness": map[string]int{"value": brightness},
+	})
+	if err != nil {
+		return err
+	}
+	return c.request("PUT", c.url(stateURL), bytes.NewReader(body), nil)
+}
+
 func (c *auroraClient) Stop() error {
 	c.ecLock.Lock()
 	defer c.ecLock.Unlock()
@@ -159,11 +238,14 @@ func (c *auroraClient) request(method, url string, reader io.Reader, target inte
 	if response.StatusCode == http.StatusForbidden {
 		return fmt.Errorf("error: not properly authenticated to nanoleaf device")
 	}
-	if response.StatusCode != 200 {
+	if response.StatusCode != 200 && response.StatusCode != http.StatusNoContent {
 		return fmt.Errorf("error: bad status code from nanoleaf device: %d", response.StatusCode)
 	}
 
 	defer response.Body.Close()
+	if target == nil || response.StatusCode == http.StatusNoContent {
+		return nil
+	}
 	return json.NewDecoder(response.Body).Decode(target)
 }
 
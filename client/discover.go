@@ -1,33 +1,235 @@
 package client
 
 import (
-	"io/ioutil"
-	"log"
+	"bufio"
+	"bytes"
+	"fmt"
 	"net"
-	"os"
+	"net/http"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/hashicorp/mdns"
+	"github.com/miekg/dns"
+	"github.com/pkg/errors"
 )
 
-func Disocver(d time.Duration) ([]string, error) {
-	log.SetOutput(ioutil.Discard)
-	defer log.SetOutput(os.Stderr)
+// DiscoveredDevice is a nanoleaf device found on the local network. Address is
+// the URL of its API, as used for the panel URL in the configuration.
+type DiscoveredDevice struct {
+	ID      string
+	Address string
+	Model   string
+	Name    string
+}
+
+const (
+	mdnsService = "_nanoleafapi._tcp.local."
+	mdnsAddress = "224.0.0.251:5353"
+	ssdpAddress = "239.255.255.250:1900"
+	defaultPort = 16021
+)
+
+// ssdpTargets are the SSDP search targets that nanoleaf devices answer, and
+// the model of the devices that answer each.
+var ssdpTargets = map[string]string{
+	"nanoleaf_aurora:light": "NL22",
+	"nanoleaf:nl29":         "NL29",
+	"nanoleaf:nl42":         "NL42",
+	"nanoleaf:nl52":         "NL52",
+	"nanoleaf:nl59":         "NL59",
+}
+
+// Discover looks for nanoleaf devices with mDNS and SSDP at once for the given
+// duration. A device that answers more than once is listed once, with what
+// each answer says about it. It fails only when neither way of looking could
+// be used.
+func Discover(d time.Duration) ([]DiscoveredDevice, error) {
+	var wg sync.WaitGroup
+	var mdnsDevices, ssdpDevices []DiscoveredDevice
+	var mdnsErr, ssdpErr error
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		mdnsDevices, mdnsErr = discoverMDNS(mdnsAddress, d)
+	}()
+	go func() {
+		defer wg.Done()
+		ssdpDevices, ssdpErr = discoverSSDP(ssdpAddress, d)
+	}()
+	wg.Wait()
+	if mdnsErr != nil && ssdpErr != nil {
+		return nil, fmt.Errorf("error: could not discover devices: mdns: %s, ssdp: %s", mdnsErr, ssdpErr)
+	}
+	return mergeDevices(append(mdnsDevices, ssdpDevices...)), nil
+}
+
+// mergeDevices combines the answers of each device, by device ID or, when a
+// device did not give one, by address.
+func mergeDevices(found []DiscoveredDevice) []DiscoveredDevice {
+	merged := []DiscoveredDevice{}
+	for _, device := range found {
+		i := 0
+		for ; i < len(merged); i++ {
+			if (device.ID != "" && strings.EqualFold(merged[i].ID, device.ID)) || merged[i].Address == device.Address {
+				break
+			}
+		}
+		if i == len(merged) {
+			merged = append(merged, device)
+			continue
+		}
+		if merged[i].ID == "" {
+			merged[i].ID = device.ID
+		}
+		if merged[i].Model == "" {
+			merged[i].Model = device.Model
+		}
+		if merged[i].Name == "" {
+			merged[i].Name = device.Name
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Address < merged[j].Address
+	})
+	return merged
+}
 
-	ch := make(chan *mdns.ServiceEntry, 5)
-	defer close(ch)
-	mdns.Lookup("_nanoleafapi._tcp", ch)
+// discoverMDNS asks for nanoleaf services with a legacy unicast mDNS query, so
+// that answers come back to the port the query was sent from.
+func discoverMDNS(address string, d time.Duration) ([]DiscoveredDevice, error) {
+	group, err := net.ResolveUDPAddr("udp4", address)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not listen for mdns answers")
+	}
+	defer conn.Close()
 
-	timeout := time.After(d)
-	possibilities := []string{}
+	query := new(dns.Msg)
+	query.SetQuestion(mdnsService, dns.TypePTR)
+	query.RecursionDesired = false
+	packet, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.WriteToUDP(packet, group); err != nil {
+		return nil, errors.Wrap(err, "could not send mdns query")
+	}
+
+	instances := map[string]bool{}
+	services := map[string]*dns.SRV{}
+	texts := map[string][]string{}
+	hosts := map[string]net.IP{}
+	sources := map[string]net.IP{}
+	conn.SetReadDeadline(time.Now().Add(d))
+	buffer := make([]byte, 65536)
 	for {
-		select {
-		case entry := <-ch:
-			possibility := "http://" + net.JoinHostPort(entry.AddrV4.String(), strconv.Itoa(entry.Port))
-			possibilities = append(possibilities, possibility)
-		case <-timeout:
-			return possibilities, nil
+		n, from, err := conn.ReadFromUDP(buffer)
+		if err != nil {
+			break
+		}
+		answer := new(dns.Msg)
+		if err := answer.Unpack(buffer[:n]); err != nil {
+			continue
+		}
+		for _, record := range append(answer.Answer, answer.Extra...) {
+			switch rr := record.(type) {
+			case *dns.PTR:
+				if strings.EqualFold(rr.Hdr.Name, mdnsService) {
+					instances[rr.Ptr] = true
+					sources[rr.Ptr] = from.IP
+				}
+			case *dns.SRV:
+				services[rr.Hdr.Name] = rr
+			case *dns.TXT:
+				texts[rr.Hdr.Name] = rr.Txt
+			case *dns.A:
+				hosts[rr.Hdr.Name] = rr.A
+			}
+		}
+	}
+
+	devices := []DiscoveredDevice{}
+	for instance := range instances {
+		ip, port := sources[instance], defaultPort
+		if service, ok := services[instance]; ok {
+			port = int(service.Port)
+			if host, ok := hosts[service.Target]; ok {
+				ip = host
+			}
+		}
+		device := DiscoveredDevice{
+			Address: "http://" + net.JoinHostPort(ip.String(), strconv.Itoa(port)),
+			Name:    strings.Replace(strings.TrimSuffix(instance, "."+mdnsService), `\ `, " ", -1),
+		}
+		for _, field := range texts[instance] {
+			parts := strings.SplitN(field, "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			switch parts[0] {
+			case "id":
+				device.ID = parts[1]
+			case "md":
+				device.Model = parts[1]
+			}
+		}
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
+
+// discoverSSDP searches for every nanoleaf search target.
+func discoverSSDP(address string, d time.Duration) ([]DiscoveredDevice, error) {
+	group, err := net.ResolveUDPAddr("udp4", address)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not listen for ssdp answers")
+	}
+	defer conn.Close()
+
+	for target := range ssdpTargets {
+		search := fmt.Sprintf("M-SEARCH * HTTP/1.1\r\nHOST: %s\r\nMAN: \"ssdp:discover\"\r\nMX: 1\r\nST: %s\r\n\r\n", ssdpAddress, target)
+		if _, err := conn.WriteToUDP([]byte(search), group); err != nil {
+			return nil, errors.Wrap(err, "could not send ssdp search")
+		}
+	}
+
+	devices := []DiscoveredDevice{}
+	conn.SetReadDeadline(time.Now().Add(d))
+	buffer := make([]byte, 8192)
+	for {
+		n, from, err := conn.ReadFromUDP(buffer)
+		if err != nil {
+			break
+		}
+		response, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(buffer[:n])), nil)
+		if err != nil {
+			continue
+		}
+		response.Body.Close()
+		model, ok := ssdpTargets[strings.ToLower(response.Header.Get("ST"))]
+		if !ok {
+			continue
+		}
+		device := DiscoveredDevice{
+			ID:      response.Header.Get("Nl-Deviceid"),
+			Address: strings.TrimSuffix(response.Header.Get("Location"), "/"),
+			Model:   model,
+			Name:    response.Header.Get("Nl-Devicename"),
+		}
+		if device.Address == "" {
+			device.Address = "http://" + net.JoinHostPort(from.IP.String(), strconv.Itoa(defaultPort))
 		}
+		devices = append(devices, device)
 	}
+	return devices, nil
 }
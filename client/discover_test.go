@@ -0,0 +1,182 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// respond answers every packet sent to a local UDP address with what answer
+// returns for it, until the returned connection is closed.
+func respond(t *testing.T, answer func(packet []byte) []byte) *net.UDPConn {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		buffer := make([]byte, 65536)
+		for {
+			n, from, err := conn.ReadFromUDP(buffer)
+			if err != nil {
+				return
+			}
+			if reply := answer(buffer[:n]); reply != nil {
+				conn.WriteToUDP(reply, from)
+			}
+		}
+	}()
+	return conn
+}
+
+func TestDiscoverMDNS(t *testing.T) {
+	instance := `Hall\ Aurora.` + mdnsService
+	responder := respond(t, func(packet []byte) []byte {
+		query := new(dns.Msg)
+		if err := query.Unpack(packet); err != nil || len(query.Question) != 1 || query.Question[0].Name != mdnsService || query.Question[0].Qtype != dns.TypePTR {
+			return nil
+		}
+		answer := new(dns.Msg)
+		answer.SetReply(query)
+		answer.Answer = []dns.RR{
+			&dns.PTR{Hdr: dns.RR_Header{Name: mdnsService, Rrtype: dns.TypePTR, Class: dns.ClassINET, Ttl: 120}, Ptr: instance},
+		}
+		answer.Extra = []dns.RR{
+			&dns.SRV{Hdr: dns.RR_Header{Name: instance, Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: 120}, Port: 16021, Target: "hall.local."},
+			&dns.TXT{Hdr: dns.RR_Header{Name: instance, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 120}, Txt: []string{"id=AA:BB", "srcvers=3.1.0"}},
+			&dns.A{Hdr: dns.RR_Header{Name: "hall.local.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 120}, A: net.IPv4(10, 0, 0, 5)},
+		}
+		packet, err := answer.Pack()
+		if err != nil {
+			return nil
+		}
+		return packet
+	})
+	defer responder.Close()
+
+	devices, err := discoverMDNS(responder.LocalAddr().String(), 300*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DiscoveredDevice{{ID: "AA:BB", Address: "http://10.0.0.5:16021", Name: "Hall Aurora"}}
+	if !reflect.DeepEqual(devices, want) {
+		t.Errorf("discoverMDNS() = %+v, want %+v", devices, want)
+	}
+}
+
+func TestDiscoverSSDP(t *testing.T) {
+	responder := respond(t, func(packet []byte) []byte {
+		request, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(packet)))
+		if err != nil || request.Method != "M-SEARCH" || request.Header.Get("ST") != "nanoleaf_aurora:light" {
+			return nil
+		}
+		return []byte("HTTP/1.1 200 OK\r\nST: nanoleaf_aurora:light\r\nLocation: http://10.0.0.5:16021/\r\nNL-DeviceID: aa:bb\r\n\r\n")
+	})
+	defer responder.Close()
+
+	devices, err := discoverSSDP(responder.LocalAddr().String(), 300*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DiscoveredDevice{{ID: "aa:bb", Address: "http://10.0.0.5:16021", Model: "NL22"}}
+	if !reflect.DeepEqual(devices, want) {
+		t.Errorf("discoverSSDP() = %+v, want %+v", devices, want)
+	}
+}
+
+func TestMergeDevices(t *testing.T) {
+	tests := []struct {
+		name  string
+		found []DiscoveredDevice
+		want  []DiscoveredDevice
+	}{
+		{
+			name: "merges the answers of a device by id",
+			found: []DiscoveredDevice{
+				{ID: "AA:BB", Address: "http://10.0.0.5:16021", Name: "Hall Aurora"},
+				{ID: "aa:bb", Address: "http://hall.local:16021", Model: "NL22", Name: "Hall"},
+			},
+			want: []DiscoveredDevice{
+				{ID: "AA:BB", Address: "http://10.0.0.5:16021", Model: "NL22", Name: "Hall Aurora"},
+			},
+		},
+		{
+			name: "merges answers without an id by address",
+			found: []DiscoveredDevice{
+				{Address: "http://10.0.0.5:16021", Model: "NL22"},
+				{ID: "aa:bb", Address: "http://10.0.0.5:16021", Name: "Hall"},
+			},
+			want: []DiscoveredDevice{
+				{ID: "aa:bb", Address: "http://10.0.0.5:16021", Model: "NL22", Name: "Hall"},
+			},
+		},
+		{
+			name: "keeps different devices apart, sorted by address",
+			found: []DiscoveredDevice{
+				{ID: "cc:dd", Address: "http://10.0.0.6:16021"},
+				{ID: "aa:bb", Address: "http://10.0.0.5:16021"},
+			},
+			want: []DiscoveredDevice{
+				{ID: "aa:bb", Address: "http://10.0.0.5:16021"},
+				{ID: "cc:dd", Address: "http://10.0.0.6:16021"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeDevices(tt.found); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeDevices() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDiscoverMerged looks for the same device with both mDNS and SSDP, and
+// expects it once with what each answer says about it.
+func TestDiscoverMerged(t *testing.T) {
+	instance := "Hall." + mdnsService
+	mdns := respond(t, func(packet []byte) []byte {
+		query := new(dns.Msg)
+		if err := query.Unpack(packet); err != nil {
+			return nil
+		}
+		answer := new(dns.Msg)
+		answer.SetReply(query)
+		answer.Answer = []dns.RR{
+			&dns.PTR{Hdr: dns.RR_Header{Name: mdnsService, Rrtype: dns.TypePTR, Class: dns.ClassINET}, Ptr: instance},
+			&dns.SRV{Hdr: dns.RR_Header{Name: instance, Rrtype: dns.TypeSRV, Class: dns.ClassINET}, Port: 16021, Target: "hall.local."},
+			&dns.TXT{Hdr: dns.RR_Header{Name: instance, Rrtype: dns.TypeTXT, Class: dns.ClassINET}, Txt: []string{"id=AA:BB"}},
+			&dns.A{Hdr: dns.RR_Header{Name: "hall.local.", Rrtype: dns.TypeA, Class: dns.ClassINET}, A: net.IPv4(10, 0, 0, 5)},
+		}
+		reply, _ := answer.Pack()
+		return reply
+	})
+	defer mdns.Close()
+	ssdp := respond(t, func(packet []byte) []byte {
+		if !strings.Contains(string(packet), "ST: nanoleaf:nl29\r\n") {
+			return nil
+		}
+		return []byte("HTTP/1.1 200 OK\r\nST: nanoleaf:nl29\r\nLocation: http://10.0.0.5:16021\r\nNL-DeviceID: aa:bb\r\nNL-DeviceName: Canvas\r\n\r\n")
+	})
+	defer ssdp.Close()
+
+	mdnsDevices, err := discoverMDNS(mdns.LocalAddr().String(), 300*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ssdpDevices, err := discoverSSDP(ssdp.LocalAddr().String(), 300*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := mergeDevices(append(mdnsDevices, ssdpDevices...))
+	want := []DiscoveredDevice{{ID: "AA:BB", Address: "http://10.0.0.5:16021", Model: "NL29", Name: "Hall"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("merged devices = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,87 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	touchEventsURL = "/%s/events?id=4"
+)
+
+// ErrTouchUnsupported is returned when the device does not report touch events.
+var ErrTouchUnsupported = errors.New("error: device does not support touch events")
+
+// TouchEvents streams the ID of each panel that is touched until stop is
+// closed. Touch events are only reported by devices with touch sensitive
+// panels.
+func (c *auroraClient) TouchEvents(stop <-chan struct{}) (<-chan int, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	request, err := http.NewRequest("GET", c.url(touchEventsURL), nil)
+	if err != nil {
+		cancel()
+		return nil, errors.Wrap(err, "could not create request")
+	}
+	response, err := http.DefaultClient.Do(request.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusForbidden:
+		response.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("error: not properly authenticated to nanoleaf device")
+	case http.StatusNotFound, http.StatusBadRequest:
+		response.Body.Close()
+		cancel()
+		return nil, ErrTouchUnsupported
+	default:
+		response.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("error: bad status code from nanoleaf device: %d", response.StatusCode)
+	}
+
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	touches := make(chan int)
+	go func() {
+		defer close(touches)
+		defer response.Body.Close()
+
+		// Events are sent as server-sent events, one JSON document per data line.
+		scanner := bufio.NewScanner(response.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !strings.HasPrefix(line, "data:") {
+				continue
+			}
+			dat := struct {
+				Events []struct {
+					PanelID int `json:"panelId"`
+				} `json:"events"`
+			}{}
+			if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &dat); err != nil {
+				continue
+			}
+			for _, event := range dat.Events {
+				select {
+				case touches <- event.PanelID:
+				case <-stop:
+					return
+				}
+			}
+		}
+	}()
+	return touches, nil
+}
@@ -10,18 +10,21 @@ import (
 )
 
 type PanelColorCommand struct {
-	ID, R, G, B byte
+	ID      int
+	R, G, B byte
 }
 
 type externalCommand struct {
 	address string
 	ch      chan *PanelColorCommand
+	frames  chan []*PanelColorCommand
 	t       tomb.Tomb
 	mu      sync.Mutex
 }
 
 type ExternalCommand interface {
 	Execute(panel, r, g, b byte) error
+	ExecuteFrame(commands []*PanelColorCommand) error
 	Stop() error
 }
 
@@ -29,6 +32,7 @@ func NewExternalCommander(address string) ExternalCommand {
 	ec := &externalCommand{
 		address: address,
 		ch:      make(chan *PanelColorCommand),
+		frames:  make(chan []*PanelColorCommand),
 	}
 	ec.t.Go(ec.loop)
 	return ec
@@ -38,7 +42,18 @@ func (ec *externalCommand) Execute(panel, r, g, b byte) error {
 	ec.mu.Lock()
 	defer ec.mu.Unlock()
 	if ec.t.Alive() {
-		ec.ch <- &PanelColorCommand{panel, r, g, b}
+		ec.ch <- &PanelColorCommand{int(panel), r, g, b}
+	}
+	return nil
+}
+
+// ExecuteFrame sends a set of panel colors immediately instead of waiting for
+// the next batch of individual commands.
+func (ec *externalCommand) ExecuteFrame(commands []*PanelColorCommand) error {
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+	if ec.t.Alive() {
+		ec.frames <- commands
 	}
 	return nil
 }
@@ -54,20 +69,26 @@ func (ec *externalCommand) loop() error {
 				"count": len(updates),
 			}).Debug("publishing commands")
 			if len(updates) > 0 {
-				conn, err := net.Dial("udp", ec.address)
-				if err != nil {
+				if err := ec.write(updates); err != nil {
 					log.WithError(err).Error("unable to connect to aurora")
 					continue
 				}
-				conn.Write(buildUDPPacket(updates))
-				conn.Close()
 				updates = map[byte][]byte{}
 			}
 		case command := <-ec.ch:
-			updates[command.ID] = []byte{1, command.R, command.G, command.B, 0, 1}
+			updates[byte(command.ID)] = []byte{1, command.R, command.G, command.B, 0, 1}
+		case commands := <-ec.frames:
+			frame := map[byte][]byte{}
+			for _, command := range commands {
+				frame[byte(command.ID)] = []byte{1, command.R, command.G, command.B, 0, 1}
+			}
+			if err := ec.write(frame); err != nil {
+				log.WithError(err).Error("unable to connect to aurora")
+			}
 		case <-ec.t.Dying():
 			log.WithField("count", len(updates)).Info("Stopping aurora client")
 			close(ec.ch)
+			close(ec.frames)
 			return nil
 		}
 	}
@@ -81,6 +102,16 @@ func (ec *externalCommand) Stop() error {
 	return ec.t.Wait()
 }
 
+func (ec *externalCommand) write(updates map[byte][]byte) error {
+	conn, err := net.Dial("udp", ec.address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write(buildUDPPacket(updates))
+	return err
+}
+
 func buildUDPPacket(dat map[byte][]byte) []byte {
 	buf := make([]byte, 0, (len(dat)*6)+1)
 	buf = append(buf, byte(len(dat)))
@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ngerakines/auroraops"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	ackFor   time.Duration
+	ackClear bool
+)
+
+var ackCmd = &cobra.Command{
+	Use:   "ack <thing>",
+	Short: "Acknowledge the current status of a thing on a running server.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		thing := args[0]
+		controlClient := auroraops.NewControlClient(viper.GetString("control.address"))
+
+		if ackClear {
+			if err := controlClient.Unacknowledge(thing); err != nil {
+				log.WithError(err).WithField("thing", thing).Error("Could not clear acknowledgement.")
+				os.Exit(1)
+			}
+			fmt.Printf("Cleared acknowledgement of %s\n", thing)
+			return
+		}
+
+		until, err := controlClient.Acknowledge(thing, ackFor)
+		if err != nil {
+			log.WithError(err).WithField("thing", thing).Error("Could not acknowledge thing.")
+			os.Exit(1)
+		}
+		if until.IsZero() {
+			fmt.Printf("Acknowledged %s until its status changes\n", thing)
+			return
+		}
+		fmt.Printf("Acknowledged %s until %s\n", thing, until.Local().Format("15:04:05"))
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(ackCmd)
+
+	ackCmd.Flags().StringVar(&cfgFile, "config", "", "config file (default is ./auroraops.yaml)")
+	ackCmd.Flags().DurationVar(&ackFor, "for", 0, "how long to acknowledge the thing for (default is until its status changes)")
+	ackCmd.Flags().BoolVar(&ackClear, "clear", false, "clear the acknowledgement instead")
+}
@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ngerakines/auroraops"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Work with the configuration file.",
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of the configuration file.",
+	Run: func(cmd *cobra.Command, args []string) {
+		schema, err := json.MarshalIndent(auroraops.ConfigSchema(), "", "  ")
+		if err != nil {
+			log.WithError(err).Error("Could not compose schema.")
+			os.Exit(1)
+		}
+		fmt.Println(string(schema))
+	},
+}
+
+// checkSchema checks the loaded configuration against the schema, logging
+// each problem located in the configuration file, and returns false when any of
+// them is an error. Unknown top-level keys are only warned about here, so that
+// the server keeps starting with them; validate reports them as errors.
+func checkSchema(v *viper.Viper) bool {
+	diagnostics := auroraops.CheckSchema(v.AllSettings())
+	if file := v.ConfigFileUsed(); file != "" && len(diagnostics) > 0 {
+		if err := auroraops.LocateDiagnostics(diagnostics, file); err != nil {
+			log.WithError(err).Warn("Could not locate problems in the configuration file.")
+		}
+	}
+	valid := true
+	for _, diagnostic := range diagnostics {
+		if len(diagnostic.Path) == 1 && diagnostic.Message == "unknown key" {
+			diagnostic.Severity = auroraops.SeverityWarning
+		}
+		if diagnostic.Severity == auroraops.SeverityError {
+			valid = false
+			log.Error(diagnostic.String())
+		} else {
+			log.Warn(diagnostic.String())
+		}
+	}
+	return valid
+}
+
+func init() {
+	RootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configSchemaCmd)
+}
@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ngerakines/auroraops"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var devicesCmd = &cobra.Command{
+	Use:   "devices",
+	Short: "Show whether each aurora of a running server is online.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		controlClient := auroraops.NewControlClient(viper.GetString("control.address"))
+		devices, err := controlClient.Devices()
+		if err != nil {
+			log.WithError(err).Error("Could not get devices.")
+			os.Exit(1)
+		}
+		for _, device := range devices {
+			state := "online"
+			if !device.Online {
+				state = "offline"
+			}
+			name := device.Name
+			if name == "" {
+				name = "-"
+			}
+			fmt.Printf("%-16s %-8s %s\n", name, state, device.URL)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(devicesCmd)
+
+	devicesCmd.Flags().StringVar(&cfgFile, "config", "", "config file (default is ./auroraops.yaml)")
+}
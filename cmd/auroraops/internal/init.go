@@ -0,0 +1,335 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ngerakines/auroraops"
+	"github.com/ngerakines/auroraops/client"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v3"
+)
+
+var (
+	initAddresses []string
+	initDiscover  time.Duration
+	initPairing   time.Duration
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init <file>",
+	Short: "Performs first-time setup.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		file := args[0]
+		data, err := ioutil.ReadFile(file)
+		if err != nil && !os.IsNotExist(err) {
+			log.WithError(err).Error("Could not read configuration file.")
+			os.Exit(1)
+		}
+		if len(data) == 0 && strings.EqualFold(filepath.Ext(file), ".json") {
+			data = []byte("{}")
+		}
+		configured, err := readDevices(data)
+		if err != nil {
+			log.WithError(err).Error("Could not parse device configuration.")
+			os.Exit(1)
+		}
+
+		s := &initSession{
+			keys:  make(map[string]string),
+			infos: make(map[string]*client.HardwareInfo),
+			found: make(map[string]client.DiscoveredDevice),
+			input: bufio.NewReader(os.Stdin),
+		}
+		for _, device := range configured {
+			s.keys[device.URL] = device.Key
+			s.add(device.URL)
+		}
+		for _, address := range initAddresses {
+			s.add(deviceURL(address))
+		}
+		fmt.Println("Looking for auroras...")
+		found, err := client.Discover(initDiscover)
+		if err != nil {
+			log.WithError(err).Warn("Could not discover auroras.")
+		}
+		for _, device := range found {
+			s.found[device.Address] = device
+			s.add(device.Address)
+		}
+
+		chosen, err := s.choose()
+		if err != nil {
+			log.WithError(err).Error("No aurora was chosen.")
+			os.Exit(1)
+		}
+
+		for _, url := range chosen {
+			if s.infos[url] != nil {
+				continue
+			}
+			if err := s.pair(url); err != nil {
+				log.WithError(err).WithField("url", url).Error("Could not pair with aurora.")
+				os.Exit(1)
+			}
+		}
+
+		// Devices already in the file keep their place, since panel IDs of
+		// every device but the first refer to it.
+		devices := []auroraops.DeviceConfig{}
+		for _, device := range configured {
+			device.Key = s.keys[device.URL]
+			devices = append(devices, device)
+		}
+		for _, url := range chosen {
+			if !containsDevice(devices, url) {
+				devices = append(devices, auroraops.DeviceConfig{URL: url, Key: s.keys[url]})
+			}
+		}
+		if len(devices) > 1 {
+			s.name(devices)
+		}
+
+		data, err = auroraops.UpdateDevices(data, devices)
+		if err != nil {
+			log.WithError(err).Error("Could not update configuration file.")
+			os.Exit(1)
+		}
+		if err := ioutil.WriteFile(file, data, 0644); err != nil {
+			log.WithError(err).Error("Could not write configuration file.")
+			os.Exit(1)
+		}
+		log.WithFields(log.Fields{
+			"file":    file,
+			"devices": len(devices),
+		}).Info("Configuration file initialized")
+	},
+}
+
+// initSession holds the auroras found while setting up, by URL.
+type initSession struct {
+	urls  []string
+	keys  map[string]string
+	infos map[string]*client.HardwareInfo
+	found map[string]client.DiscoveredDevice
+	input *bufio.Reader
+}
+
+// add adds an aurora, reading its information when its key is known.
+func (s *initSession) add(url string) {
+	for _, known := range s.urls {
+		if known == url {
+			return
+		}
+	}
+	s.urls = append(s.urls, url)
+	if key := s.keys[url]; key != "" {
+		if info, err := readInfo(url, key); err == nil {
+			s.infos[url] = info
+		} else {
+			log.WithError(err).WithField("url", url).Debug("Could not read configured aurora.")
+		}
+	}
+}
+
+// choose lists the auroras and asks which to set up. An answer that is not a
+// list of numbers is taken as the address of another aurora.
+func (s *initSession) choose() ([]string, error) {
+	for {
+		if len(s.urls) == 0 {
+			fmt.Print("No auroras were found. Enter the address of one: ")
+		} else {
+			for i, url := range s.urls {
+				fmt.Printf("%3d) %s %s\n", i+1, url, s.describe(url))
+			}
+			fmt.Print("Choose auroras by number, enter an address to add one, or press enter for all: ")
+		}
+		line, err := s.input.ReadString('\n')
+		if err != nil && line == "" {
+			fmt.Println()
+			return nil, errors.New("input ended")
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if len(s.urls) == 0 {
+				continue
+			}
+			return s.urls, nil
+		}
+		if chosen, ok := s.numbers(line); ok {
+			return chosen, nil
+		}
+		s.add(deviceURL(line))
+	}
+}
+
+// numbers reads a list of numbers from the listed auroras.
+func (s *initSession) numbers(line string) ([]string, bool) {
+	chosen := []string{}
+	for _, field := range strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' }) {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, false
+		}
+		if n < 1 || n > len(s.urls) {
+			fmt.Printf("There is no aurora %d.\n", n)
+			return nil, false
+		}
+		if !containsString(chosen, s.urls[n-1]) {
+			chosen = append(chosen, s.urls[n-1])
+		}
+	}
+	return chosen, true
+}
+
+func (s *initSession) describe(url string) string {
+	info := s.infos[url]
+	if info == nil {
+		if device, ok := s.found[url]; ok && device.Name != "" {
+			return fmt.Sprintf("%s (%s, not paired)", device.Name, device.Model)
+		}
+		return "(not paired)"
+	}
+	return fmt.Sprintf("%s (%s, serial %s, firmware %s)", info.Name, info.Model, info.SerialNo, info.FirmwareVersion)
+}
+
+// pair asks for an aurora to be put in pairing mode and keeps asking it for a
+// key until it gives one or the pairing time runs out.
+func (s *initSession) pair(url string) error {
+	auroraClient, err := client.New(url)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Hold the power button of %s for 5 to 7 seconds, until its lights flash, to pair with it.\n", url)
+	deadline := time.Now().Add(initPairing)
+	for {
+		token, err := auroraClient.Authorize()
+		if err == nil {
+			s.keys[url] = token
+			break
+		}
+		if time.Now().After(deadline) {
+			return errors.Wrap(err, "pairing timed out")
+		}
+		log.WithError(err).Debug("Aurora is not in pairing mode yet.")
+		time.Sleep(2 * time.Second)
+	}
+	info, err := readInfo(url, s.keys[url])
+	if err != nil {
+		return err
+	}
+	s.infos[url] = info
+	fmt.Printf("Paired with %s %s\n", url, s.describe(url))
+	return nil
+}
+
+// name gives every unnamed device a unique name, taken from the name the
+// aurora reports, or the name it was discovered with, when either is known.
+func (s *initSession) name(devices []auroraops.DeviceConfig) {
+	used := make(map[string]bool)
+	for _, device := range devices {
+		used[device.Name] = true
+	}
+	for i := range devices {
+		if devices[i].Name != "" {
+			continue
+		}
+		base := "aurora"
+		if info := s.infos[devices[i].URL]; info != nil && deviceName(info.Name) != "" {
+			base = deviceName(info.Name)
+		} else if device, ok := s.found[devices[i].URL]; ok && deviceName(device.Name) != "" {
+			base = deviceName(device.Name)
+		}
+		name := base
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%s-%d", base, n)
+		}
+		used[name] = true
+		devices[i].Name = name
+	}
+}
+
+// readDevices returns the devices of a configuration file, which are either
+// its devices section or the aurora of its panel section.
+func readDevices(data []byte) ([]auroraops.DeviceConfig, error) {
+	config := struct {
+		Panel struct {
+			URL string `yaml:"url"`
+			Key string `yaml:"key"`
+		} `yaml:"panel"`
+		Devices interface{} `yaml:"devices"`
+	}{}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, errors.Wrap(err, "could not parse configuration")
+	}
+	if config.Devices != nil {
+		return auroraops.ParseDeviceConfig(config.Devices)
+	}
+	if config.Panel.URL != "" {
+		return []auroraops.DeviceConfig{{URL: config.Panel.URL, Key: config.Panel.Key}}, nil
+	}
+	return []auroraops.DeviceConfig{}, nil
+}
+
+func readInfo(url, key string) (*client.HardwareInfo, error) {
+	auroraClient, err := client.NewReadOnly(url, key)
+	if err != nil {
+		return nil, err
+	}
+	return auroraClient.GetInfo()
+}
+
+// deviceURL turns an address given by hand into the URL of an aurora, using
+// the default port when none is given.
+func deviceURL(address string) string {
+	if strings.Contains(address, "://") {
+		return strings.TrimSuffix(address, "/")
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(strings.Trim(address, "[]"), "16021")
+	}
+	return "http://" + address
+}
+
+// deviceName makes a device name out of the name an aurora reports.
+func deviceName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.Join(strings.Fields(name), "-")
+	return strings.Replace(name, ":", "", -1)
+}
+
+func containsDevice(devices []auroraops.DeviceConfig, url string) bool {
+	for _, device := range devices {
+		if device.URL == url {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(s []string, e string) bool {
+	for _, a := range s {
+		if a == e {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	RootCmd.AddCommand(initCmd)
+
+	initCmd.Flags().StringSliceVar(&initAddresses, "address", nil, "address of an aurora to add to those discovered")
+	initCmd.Flags().DurationVar(&initDiscover, "discover", 5*time.Second, "how long to look for auroras")
+	initCmd.Flags().DurationVar(&initPairing, "pairing", 2*time.Minute, "how long to wait for an aurora to be put in pairing mode")
+}
@@ -0,0 +1,156 @@
+package internal
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/ngerakines/auroraops"
+	"github.com/ngerakines/auroraops/client"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	layoutSnapshot string
+	layoutFile     string
+	layoutRemap    bool
+)
+
+var layoutCmd = &cobra.Command{
+	Use:   "layout",
+	Short: "Work with snapshots of the panel layout.",
+}
+
+var layoutSaveCmd = &cobra.Command{
+	Use:   "save",
+	Short: "Save a snapshot of the panel layout.",
+	Run: func(cmd *cobra.Command, args []string) {
+		snapshot := snapshotFile()
+		info := currentLayout()
+		if err := auroraops.SaveLayout(snapshot, info.Panels); err != nil {
+			log.WithError(err).Error("Could not save panel layout.")
+			os.Exit(1)
+		}
+		fmt.Printf("Saved %d panels to %s\n", len(info.Panels), snapshot)
+	},
+}
+
+var layoutDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare the panel layout with the saved snapshot.",
+	Run: func(cmd *cobra.Command, args []string) {
+		snapshot := snapshotFile()
+		baseline, err := auroraops.LoadLayout(snapshot)
+		if err != nil {
+			log.WithError(err).Error("Could not load panel layout snapshot.")
+			os.Exit(1)
+		}
+		if baseline == nil {
+			log.WithField("file", snapshot).Error("No panel layout snapshot was saved, run auroraops layout save first.")
+			os.Exit(1)
+		}
+		info := currentLayout()
+
+		diff := auroraops.DiffLayouts(baseline, info.Panels)
+		if diff.Empty() {
+			fmt.Println("The panel layout matches the snapshot.")
+			return
+		}
+		for _, line := range diff.Lines() {
+			fmt.Println(line)
+		}
+		if len(diff.Remap) == 0 || !layoutRemap {
+			if len(diff.Remap) > 0 {
+				fmt.Println("The layout has the same shape with new panel IDs, run again with --remap to update the configuration.")
+			}
+			os.Exit(1)
+		}
+
+		file := viper.ConfigFileUsed()
+		if file == "" {
+			log.Error("A configuration file is needed to remap panels.")
+			os.Exit(1)
+		}
+		devices, err := loadDevices()
+		if err != nil {
+			log.WithError(err).Error("Could not parse device configuration.")
+			os.Exit(1)
+		}
+		things, err := auroraops.ParseThingConfig(viper.Get("things"), devices)
+		if err != nil {
+			log.WithError(err).Error("Could not parse thing configuration.")
+			os.Exit(1)
+		}
+		panels := make(map[string][]int)
+		for thing, thingConfig := range things {
+			if len(thingConfig.Panels) == 0 {
+				continue
+			}
+			for _, panel := range thingConfig.Panels {
+				if to, ok := diff.Remap[panel]; ok {
+					panel = to
+				}
+				panels[thing] = append(panels[thing], panel)
+			}
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			log.WithError(err).Error("Could not read configuration file.")
+			os.Exit(1)
+		}
+		data, err = auroraops.UpdateThings(data, panels, nil, devices)
+		if err != nil {
+			log.WithError(err).Error("Could not update configuration file.")
+			os.Exit(1)
+		}
+		if err := ioutil.WriteFile(file, data, 0644); err != nil {
+			log.WithError(err).Error("Could not write configuration file.")
+			os.Exit(1)
+		}
+		if err := auroraops.SaveLayout(snapshot, info.Panels); err != nil {
+			log.WithError(err).Error("Could not save panel layout.")
+			os.Exit(1)
+		}
+		fmt.Printf("Remapped things in %s and saved the layout to %s\n", file, snapshot)
+	},
+}
+
+// snapshotFile returns the layout snapshot named by flag or configuration.
+func snapshotFile() string {
+	snapshot := layoutSnapshot
+	if snapshot == "" {
+		snapshot = viper.GetString("layout.snapshot")
+	}
+	if snapshot == "" {
+		log.Error("No layout snapshot is configured, set layout.snapshot or use --snapshot.")
+		os.Exit(1)
+	}
+	return snapshot
+}
+
+// currentLayout reads the panel layout from the layout file or the aurora.
+func currentLayout() *client.HardwareInfo {
+	info, err := loadLayout(layoutFile, false)
+	if err != nil {
+		log.WithError(err).Error("Could not get panel layout.")
+		os.Exit(1)
+	}
+	if info == nil {
+		log.Error("No aurora is configured, use --layout to read a layout file.")
+		os.Exit(1)
+	}
+	return info
+}
+
+func init() {
+	RootCmd.AddCommand(layoutCmd)
+	layoutCmd.AddCommand(layoutSaveCmd)
+	layoutCmd.AddCommand(layoutDiffCmd)
+
+	layoutCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./auroraops.yaml)")
+	layoutCmd.PersistentFlags().StringVar(&layoutSnapshot, "snapshot", "", "layout snapshot file (default is layout.snapshot)")
+	layoutCmd.PersistentFlags().StringVar(&layoutFile, "layout", "", "panel layout file to use instead of the aurora")
+	layoutDiffCmd.Flags().BoolVar(&layoutRemap, "remap", false, "update panel IDs in the configuration when only the IDs changed")
+}
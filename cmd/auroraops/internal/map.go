@@ -0,0 +1,457 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	colorful "github.com/lucasb-eyer/go-colorful"
+	"github.com/ngerakines/auroraops"
+	"github.com/ngerakines/auroraops/client"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	mapTouch      bool
+	mapUnassigned bool
+	mapPanels     []string
+)
+
+const mapHelp = `Answer with the name of the thing the lit panel belongs to, or:
+  (enter)            keep the panel as it is
+  -                  remove the panel from its thing
+  <                  go back to the previous panel
+  :rename OLD NEW    rename a thing
+  :done              stop and write the configuration file
+  :quit              stop without writing anything`
+
+var mapCmd = &cobra.Command{
+	Use:   "map",
+	Short: "Assign panels to things by lighting them one at a time.",
+	Run: func(cmd *cobra.Command, args []string) {
+		file := viper.ConfigFileUsed()
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".yaml", ".yml", ".json":
+		case "":
+			log.Error("A configuration file is needed to write things to.")
+			os.Exit(1)
+		default:
+			log.WithField("file", file).Error("Only YAML and JSON configuration files can be written to.")
+			os.Exit(1)
+		}
+
+		auroraClient, devices := openDevices()
+		things, err := auroraops.ParseThingConfig(viper.Get("things"), devices)
+		if err != nil {
+			log.WithError(err).Error("Could not parse thing configuration.")
+			os.Exit(1)
+		}
+		only := make([]int, 0, len(mapPanels))
+		for _, ref := range mapPanels {
+			panel, err := auroraops.ParsePanelRef(devices, ref)
+			if err != nil {
+				log.WithError(err).Error("Invalid panel.")
+				os.Exit(1)
+			}
+			only = append(only, panel)
+		}
+
+		if err := auroraClient.Start(); err != nil {
+			log.WithError(err).Error("Could not connect to aurora.")
+			os.Exit(1)
+		}
+		panelInfo, err := auroraClient.GetInfo()
+		if err != nil {
+			log.WithError(err).Error("Could not get panel info")
+			os.Exit(1)
+		}
+
+		m := newMapper(auroraClient, devices, panelInfo.Panels, things)
+		m.only = only
+		fmt.Println(mapHelp)
+		fmt.Println()
+		if mapTouch {
+			err = m.touch()
+		} else {
+			err = m.walk()
+		}
+		m.clear()
+		if stopErr := auroraClient.Stop(); stopErr != nil {
+			log.WithError(stopErr).Error("Could not stop aurora client.")
+		}
+		if err == errMapQuit {
+			fmt.Println("Nothing was written.")
+			return
+		}
+		if err != nil {
+			log.WithError(err).Error("Could not map panels.")
+			os.Exit(1)
+		}
+
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			log.WithError(err).Error("Could not read configuration file.")
+			os.Exit(1)
+		}
+		data, err = auroraops.UpdateThings(data, m.thingPanels(), m.renamed, devices)
+		if err != nil {
+			log.WithError(err).Error("Could not update configuration file.")
+			os.Exit(1)
+		}
+		if err := ioutil.WriteFile(file, data, 0644); err != nil {
+			log.WithError(err).Error("Could not write configuration file.")
+			os.Exit(1)
+		}
+		m.summary()
+		fmt.Printf("Wrote things to %s\n", file)
+	},
+}
+
+var (
+	errMapQuit = fmt.Errorf("quit")
+	errMapDone = fmt.Errorf("done")
+	errMapBack = fmt.Errorf("back")
+	// errMapAgain asks for the same panel again.
+	errMapAgain = fmt.Errorf("again")
+)
+
+// mapper holds the assignment of panels to things while mapping. Only things
+// on the base layer that list their panels are mapped; layered things, things
+// that cover all panels and things that select panels by geometry are left as
+// they are.
+type mapper struct {
+	auroraClient client.AuroraClient
+	devices      []auroraops.DeviceConfig
+	panels       []*client.Panel
+	// only limits the panels walked to these, when set.
+	only       []int
+	assignment map[int]string
+	mapped     map[string]bool
+	fixed      map[string]bool
+	// renamed maps the names of things in the configuration file to their new
+	// names.
+	renamed map[string]string
+	input   *bufio.Reader
+}
+
+func newMapper(auroraClient client.AuroraClient, devices []auroraops.DeviceConfig, panels []*client.Panel, things map[string]auroraops.ThingConfigSet) *mapper {
+	m := &mapper{
+		auroraClient: auroraClient,
+		devices:      devices,
+		panels:       append([]*client.Panel{}, panels...),
+		assignment:   make(map[int]string),
+		mapped:       make(map[string]bool),
+		fixed:        make(map[string]bool),
+		renamed:      make(map[string]string),
+		input:        bufio.NewReader(os.Stdin),
+	}
+	// Panels are visited from the top left, row by row.
+	sort.SliceStable(m.panels, func(i, j int) bool {
+		if m.panels[i].Y != m.panels[j].Y {
+			return m.panels[i].Y > m.panels[j].Y
+		}
+		return m.panels[i].X < m.panels[j].X
+	})
+	for thing, thingConfig := range things {
+		if thingConfig.All || thingConfig.Priority != 0 || len(thingConfig.Select) > 0 {
+			m.fixed[thing] = true
+			continue
+		}
+		m.mapped[thing] = true
+		for _, panel := range thingConfig.Panels {
+			m.assignment[panel] = thing
+		}
+	}
+	return m
+}
+
+// walk lights each panel in turn and asks which thing it belongs to.
+func (m *mapper) walk() error {
+	visit := []int{}
+	for _, panel := range m.panels {
+		if mapUnassigned && m.assignment[panel.ID] != "" {
+			continue
+		}
+		if len(m.only) > 0 && !containsPanel(m.only, panel.ID) {
+			continue
+		}
+		visit = append(visit, panel.ID)
+	}
+	if len(visit) == 0 {
+		fmt.Println("There are no panels to map.")
+		return nil
+	}
+
+	for i := 0; i < len(visit); {
+		m.show(visit[i])
+		fmt.Printf("Panel %s (%d of %d)%s: ", m.ref(visit[i]), i+1, len(visit), m.current(visit[i]))
+		line, err := m.input.ReadString('\n')
+		if err != nil && line == "" {
+			fmt.Println()
+			return nil
+		}
+		switch err := m.answer(visit[i], line); err {
+		case nil:
+			i = i + 1
+		case errMapAgain:
+		case errMapBack:
+			if i > 0 {
+				i = i - 1
+			}
+		case errMapDone:
+			return nil
+		case errMapQuit:
+			return err
+		default:
+			fmt.Println(err)
+		}
+	}
+	return nil
+}
+
+// touch asks which thing each touched panel belongs to.
+func (m *mapper) touch() error {
+	stop := make(chan struct{})
+	defer close(stop)
+	touches, err := m.auroraClient.TouchEvents(stop)
+	if err != nil {
+		return err
+	}
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		for {
+			line, err := m.input.ReadString('\n')
+			if err != nil && line == "" {
+				return
+			}
+			select {
+			case lines <- line:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	fmt.Println("Touch a panel to assign it.")
+	m.show(-1)
+	current := -1
+	for {
+		select {
+		case panel, ok := <-touches:
+			if !ok {
+				return fmt.Errorf("touch events stopped")
+			}
+			current = panel
+			m.show(current)
+			fmt.Printf("\nPanel %s%s: ", m.ref(current), m.current(current))
+		case line, ok := <-lines:
+			if !ok {
+				fmt.Println()
+				return nil
+			}
+			if current == -1 && !strings.HasPrefix(strings.TrimSpace(line), ":") {
+				fmt.Println("Touch a panel first.")
+				continue
+			}
+			switch err := m.answer(current, line); err {
+			case nil:
+				current = -1
+				m.show(current)
+				fmt.Println("Touch a panel to assign it.")
+			case errMapAgain:
+			case errMapBack:
+				fmt.Println("Touch the panel to change instead.")
+			case errMapDone:
+				return nil
+			case errMapQuit:
+				return err
+			default:
+				fmt.Println(err)
+			}
+		}
+	}
+}
+
+// answer applies an answer to the prompt for a panel.
+func (m *mapper) answer(panel int, line string) error {
+	line = strings.TrimSpace(line)
+	fields := strings.Fields(line)
+	switch {
+	case line == "":
+		return nil
+	case line == "?":
+		fmt.Println(mapHelp)
+		return errMapAgain
+	case line == "-":
+		delete(m.assignment, panel)
+		return nil
+	case line == "<":
+		return errMapBack
+	case line == ":done":
+		return errMapDone
+	case line == ":quit":
+		return errMapQuit
+	case fields[0] == ":rename":
+		if len(fields) != 3 {
+			return fmt.Errorf("Usage: :rename OLD NEW")
+		}
+		if err := m.rename(fields[1], fields[2]); err != nil {
+			return err
+		}
+		fmt.Printf("Renamed %s to %s\n", fields[1], fields[2])
+		return errMapAgain
+	case strings.HasPrefix(line, ":"):
+		return fmt.Errorf("Unknown command %s, answer ? for help.", fields[0])
+	case len(fields) > 1:
+		return fmt.Errorf("Thing names cannot contain spaces.")
+	case m.fixed[line]:
+		return fmt.Errorf("Thing %s is a layer, covers all panels or selects panels by geometry, change it in the configuration file.", line)
+	}
+	m.assignment[panel] = line
+	m.mapped[line] = true
+	return nil
+}
+
+func (m *mapper) rename(from, to string) error {
+	if !m.mapped[from] && !m.fixed[from] {
+		return fmt.Errorf("There is no thing named %s.", from)
+	}
+	if m.mapped[to] || m.fixed[to] {
+		return fmt.Errorf("There is already a thing named %s.", to)
+	}
+	original := from
+	for name, renamed := range m.renamed {
+		if renamed == from {
+			original = name
+		}
+	}
+	if original == to {
+		delete(m.renamed, original)
+	} else {
+		m.renamed[original] = to
+	}
+
+	if m.fixed[from] {
+		delete(m.fixed, from)
+		m.fixed[to] = true
+		return nil
+	}
+	delete(m.mapped, from)
+	m.mapped[to] = true
+	for panel, thing := range m.assignment {
+		if thing == from {
+			m.assignment[panel] = to
+		}
+	}
+	return nil
+}
+
+// thingPanels returns the panels of each mapped thing.
+func (m *mapper) thingPanels() map[string][]int {
+	panels := make(map[string][]int)
+	for thing := range m.mapped {
+		panels[thing] = []int{}
+	}
+	for panel, thing := range m.assignment {
+		panels[thing] = append(panels[thing], panel)
+	}
+	for thing := range panels {
+		sort.Ints(panels[thing])
+	}
+	return panels
+}
+
+func (m *mapper) summary() {
+	panels := m.thingPanels()
+	things := make([]string, 0, len(panels))
+	for thing := range panels {
+		things = append(things, thing)
+	}
+	sort.Strings(things)
+	for _, thing := range things {
+		ids := make([]string, len(panels[thing]))
+		for i, panel := range panels[thing] {
+			ids[i] = m.ref(panel)
+		}
+		fmt.Printf("%s: %s\n", thing, strings.Join(ids, ", "))
+	}
+	for from, to := range m.renamed {
+		fmt.Printf("Renamed %s to %s; statuses reported for %s must now be reported for %s.\n", from, to, from, to)
+	}
+}
+
+func (m *mapper) ref(panel int) string {
+	return auroraops.FormatPanelRef(m.devices, panel)
+}
+
+func (m *mapper) current(panel int) string {
+	if thing, ok := m.assignment[panel]; ok {
+		return fmt.Sprintf(" [%s]", thing)
+	}
+	return ""
+}
+
+// show lights the panel being mapped white, and every other panel in a dim
+// color of its thing.
+func (m *mapper) show(current int) {
+	things := []string{}
+	for thing := range m.mapped {
+		things = append(things, thing)
+	}
+	sort.Strings(things)
+	hues := make(map[string]float64)
+	for i, thing := range things {
+		hues[thing] = 360 * float64(i) / float64(len(things))
+	}
+
+	commands := make([]*client.PanelColorCommand, 0, len(m.panels))
+	for _, panel := range m.panels {
+		color := colorful.Color{}
+		if panel.ID == current {
+			color = colorful.Color{R: 1, G: 1, B: 1}
+		} else if thing, ok := m.assignment[panel.ID]; ok {
+			color = colorful.Hsv(hues[thing], 1, 0.3)
+		}
+		r, g, b := color.Clamped().RGB255()
+		commands = append(commands, &client.PanelColorCommand{ID: panel.ID, R: r, G: g, B: b})
+	}
+	if err := m.auroraClient.SetPanelColors(commands); err != nil {
+		log.WithError(err).Warn("Could not light panels.")
+	}
+}
+
+func (m *mapper) clear() {
+	commands := make([]*client.PanelColorCommand, 0, len(m.panels))
+	for _, panel := range m.panels {
+		commands = append(commands, &client.PanelColorCommand{ID: panel.ID})
+	}
+	if err := m.auroraClient.SetPanelColors(commands); err != nil {
+		log.WithError(err).Warn("Could not clear panels.")
+	}
+}
+
+func containsPanel(panels []int, panel int) bool {
+	for _, p := range panels {
+		if p == panel {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	RootCmd.AddCommand(mapCmd)
+
+	mapCmd.Flags().StringVar(&cfgFile, "config", "", "config file (default is ./auroraops.yaml)")
+	mapCmd.Flags().BoolVar(&mapTouch, "touch", false, "assign panels as they are touched, on devices with touch sensitive panels")
+	mapCmd.Flags().BoolVar(&mapUnassigned, "unassigned", false, "only visit panels that are not assigned to a thing")
+	mapCmd.Flags().StringSliceVar(&mapPanels, "panel", nil, "only visit these panels")
+}
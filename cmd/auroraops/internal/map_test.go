@@ -0,0 +1,201 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ngerakines/auroraops"
+	"github.com/ngerakines/auroraops/client"
+)
+
+// newTestMapper creates a mapper of panels 1 to 3 with the hall on panel 1
+// and a layer called glow.
+func newTestMapper() *mapper {
+	panels := []*client.Panel{{ID: 1}, {ID: 2}, {ID: 3}}
+	things := map[string]auroraops.ThingConfigSet{
+		"hall": {Panels: []int{1}},
+		"glow": {All: true, Priority: 1},
+	}
+	return newMapper(nil, nil, panels, things)
+}
+
+func TestMapperRename(t *testing.T) {
+	tests := []struct {
+		name        string
+		renames     [][2]string
+		wantErr     bool
+		wantRenamed map[string]string
+		wantPanels  map[string][]int
+	}{
+		{
+			name:        "renames a mapped thing and its panels",
+			renames:     [][2]string{{"hall", "porch"}},
+			wantRenamed: map[string]string{"hall": "porch"},
+			wantPanels:  map[string][]int{"porch": {1}},
+		},
+		{
+			name:        "records renames of a renamed thing from its original name",
+			renames:     [][2]string{{"hall", "porch"}, {"porch", "door"}},
+			wantRenamed: map[string]string{"hall": "door"},
+			wantPanels:  map[string][]int{"door": {1}},
+		},
+		{
+			name:        "forgets a rename back to the original name",
+			renames:     [][2]string{{"hall", "porch"}, {"porch", "hall"}},
+			wantRenamed: map[string]string{},
+			wantPanels:  map[string][]int{"hall": {1}},
+		},
+		{
+			name:        "renames layers without mapping them",
+			renames:     [][2]string{{"glow", "shine"}},
+			wantRenamed: map[string]string{"glow": "shine"},
+			wantPanels:  map[string][]int{"hall": {1}},
+		},
+		{
+			name:        "rejects unknown things",
+			renames:     [][2]string{{"porch", "door"}},
+			wantErr:     true,
+			wantRenamed: map[string]string{},
+			wantPanels:  map[string][]int{"hall": {1}},
+		},
+		{
+			name:        "rejects names already taken",
+			renames:     [][2]string{{"hall", "glow"}},
+			wantErr:     true,
+			wantRenamed: map[string]string{},
+			wantPanels:  map[string][]int{"hall": {1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestMapper()
+			var err error
+			for _, rename := range tt.renames {
+				if err = m.rename(rename[0], rename[1]); err != nil {
+					break
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("rename() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(m.renamed, tt.wantRenamed) {
+				t.Errorf("renamed = %v, want %v", m.renamed, tt.wantRenamed)
+			}
+			if got := m.thingPanels(); !reflect.DeepEqual(got, tt.wantPanels) {
+				t.Errorf("thingPanels() = %v, want %v", got, tt.wantPanels)
+			}
+		})
+	}
+}
+
+func TestMapperAnswer(t *testing.T) {
+	tests := []struct {
+		name       string
+		panel      int
+		line       string
+		wantErr    error
+		wantPanels map[string][]int
+	}{
+		{
+			name:       "assigns the panel to a thing",
+			panel:      2,
+			line:       "hall\n",
+			wantPanels: map[string][]int{"hall": {1, 2}},
+		},
+		{
+			name:       "maps new things",
+			panel:      2,
+			line:       "  porch \n",
+			wantPanels: map[string][]int{"hall": {1}, "porch": {2}},
+		},
+		{
+			name:       "moves the panel to another thing",
+			panel:      1,
+			line:       "porch",
+			wantPanels: map[string][]int{"hall": {}, "porch": {1}},
+		},
+		{
+			name:       "keeps the panel as it is on an empty answer",
+			panel:      1,
+			line:       "\n",
+			wantPanels: map[string][]int{"hall": {1}},
+		},
+		{
+			name:       "unassigns the panel",
+			panel:      1,
+			line:       "-",
+			wantPanels: map[string][]int{"hall": {}},
+		},
+		{
+			name:       "goes back",
+			panel:      1,
+			line:       "<",
+			wantErr:    errMapBack,
+			wantPanels: map[string][]int{"hall": {1}},
+		},
+		{
+			name:       "asks again after help",
+			panel:      1,
+			line:       "?",
+			wantErr:    errMapAgain,
+			wantPanels: map[string][]int{"hall": {1}},
+		},
+		{
+			name:       "asks again after renaming",
+			panel:      2,
+			line:       ":rename hall porch",
+			wantErr:    errMapAgain,
+			wantPanels: map[string][]int{"porch": {1}},
+		},
+		{
+			name:       "finishes",
+			panel:      2,
+			line:       ":done",
+			wantErr:    errMapDone,
+			wantPanels: map[string][]int{"hall": {1}},
+		},
+		{
+			name:       "quits",
+			panel:      2,
+			line:       ":quit",
+			wantErr:    errMapQuit,
+			wantPanels: map[string][]int{"hall": {1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestMapper()
+			if err := m.answer(tt.panel, tt.line); err != tt.wantErr {
+				t.Fatalf("answer(%d, %q) error = %v, want %v", tt.panel, tt.line, err, tt.wantErr)
+			}
+			if got := m.thingPanels(); !reflect.DeepEqual(got, tt.wantPanels) {
+				t.Errorf("thingPanels() = %v, want %v", got, tt.wantPanels)
+			}
+		})
+	}
+}
+
+func TestMapperAnswerErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{name: "names with spaces", line: "front hall"},
+		{name: "layers", line: "glow"},
+		{name: "unknown commands", line: ":map"},
+		{name: "renames without two names", line: ":rename hall"},
+		{name: "renames of unknown things", line: ":rename porch door"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestMapper()
+			err := m.answer(2, tt.line)
+			if err == nil || err == errMapAgain || err == errMapBack || err == errMapDone || err == errMapQuit {
+				t.Errorf("answer(2, %q) error = %v, want a message", tt.line, err)
+			}
+			if got, want := m.thingPanels(), map[string][]int{"hall": {1}}; !reflect.DeepEqual(got, want) {
+				t.Errorf("thingPanels() = %v, want %v", got, want)
+			}
+		})
+	}
+}
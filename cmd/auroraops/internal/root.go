@@ -1,16 +1,21 @@
 package internal
 
 import (
-	"bufio"
+	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"os/signal"
 	"path"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
 	colorful "github.com/lucasb-eyer/go-colorful"
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/ngerakines/auroraops"
@@ -18,7 +23,6 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	yaml "gopkg.in/yaml.v2"
 )
 
 var (
@@ -30,84 +34,6 @@ var RootCmd = &cobra.Command{
 	Short: "auroraops is a tool to relay information to your nanoleaf aurora.",
 }
 
-var initCmd = &cobra.Command{
-	Use:   "init",
-	Short: "Performs first-time setup.",
-	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		hosts, err := client.Disocver(5 * time.Second)
-		if err != nil {
-			log.WithError(err).Error("Could not discover aurora.")
-			os.Exit(1)
-		}
-		if len(hosts) == 0 {
-			log.Error("No aurora hosts found.")
-			os.Exit(1)
-		}
-		if len(hosts) > 1 {
-			log.WithField("hosts", hosts).Error("More than one aurora host found.")
-			os.Exit(1)
-		}
-
-		client, err := client.New(hosts[0])
-		if err != nil {
-			log.WithError(err).Error("Could not discover aurora.")
-			os.Exit(1)
-		}
-
-		fmt.Println("Need to authenticate to device. Hold power button to enter pairing mode. Press enter when ready.")
-		reader := bufio.NewReader(os.Stdin)
-		reader.ReadString('\n')
-		token, err := client.Authorize()
-		if err != nil {
-			log.WithError(err).Error("Unable to authenticate")
-			os.Exit(1)
-		}
-
-		type Config struct {
-			Name string `json:"name" yaml:"name"` // Supporting both JSON and YAML.
-			Age  int    `json:"name" yaml:"name"`
-		}
-
-		data := struct {
-			Panel struct {
-				URL string `yaml:"url"`
-				Key string `yaml:"key"`
-			} `yaml:"panel"`
-		}{
-			Panel: struct {
-				URL string `yaml:"url"`
-				Key string `yaml:"key"`
-			}{
-				URL: hosts[0],
-				Key: token,
-			},
-		}
-
-		d, err := yaml.Marshal(&data)
-		if err != nil {
-			log.WithFields(log.Fields{
-				"token": token,
-				"host":  hosts[0],
-			}).WithError(err).Error("Unable to compose yaml")
-			os.Exit(1)
-		}
-
-		if err := ioutil.WriteFile(args[0], d, 0644); err != nil {
-			log.WithFields(log.Fields{
-				"token": token,
-				"host":  hosts[0],
-			}).WithError(err).Error("Unable to compose yaml")
-			os.Exit(1)
-		}
-		log.WithFields(log.Fields{
-			"file":  args[0],
-			"token": token,
-			"host":  hosts[0],
-		}).Info("Configuration file initialized")
-	},
-}
-
 var colors = map[string]string{
 	"white":   "#ffffff",
 	"grey":    "#808080",
@@ -128,9 +54,9 @@ var infoCmd = &cobra.Command{
 	Use:   "info",
 	Short: "Run the server.",
 	Run: func(cmd *cobra.Command, args []string) {
-		auroraClient, err := client.NewWithToken(viper.GetString("panel.url"), viper.GetString("panel.key"))
-		if err != nil {
-			log.WithError(err).Error("Could not create aurora client.")
+		auroraClient, devices := openDevices()
+		if err := auroraClient.Start(); err != nil {
+			log.WithError(err).Error("Could not connect to aurora.")
 			os.Exit(1)
 		}
 		panelInfo, err := auroraClient.GetInfo()
@@ -152,18 +78,22 @@ var infoCmd = &cobra.Command{
 		}
 
 		colorIndex := 0
+		commands := make([]*client.PanelColorCommand, 0, len(panelInfo.Panels))
 		for _, panel := range panelInfo.Panels {
-			if colorIndex > len(colorNames) {
+			if colorIndex >= len(colorNames) {
 				colorIndex = 0
 			}
 			color := colorfulColors[colorIndex]
 			colorName := colorNames[colorIndex]
-			fmt.Printf("Setting panel %d to %s\n", panel.ID, colorName)
+			fmt.Printf("Setting panel %s to %s\n", auroraops.FormatPanelRef(devices, panel.ID), colorName)
 			r, g, b := color.Clamped().RGB255()
-			auroraClient.SetPanelColor(byte(panel.ID), byte(r), byte(g), byte(b))
+			commands = append(commands, &client.PanelColorCommand{ID: panel.ID, R: r, G: g, B: b})
 
 			colorIndex = colorIndex + 1
 		}
+		if err := auroraClient.SetPanelColors(commands); err != nil {
+			log.WithError(err).Error("Could not set panel colors.")
+		}
 
 		time.Sleep(2 * time.Second)
 
@@ -178,28 +108,66 @@ var serverCmd = &cobra.Command{
 	Short: "Run the server.",
 	Run: func(cmd *cobra.Command, args []string) {
 
-		auroraClient, err := client.NewWithToken(viper.GetString("panel.url"), viper.GetString("panel.key"))
-		if err != nil {
-			log.WithError(err).Error("Could not create aurora client.")
+		if !checkSchema(viper.GetViper()) {
+			log.Error("Configuration does not match the schema.")
 			os.Exit(1)
 		}
 
-		thingManager := auroraops.NewThingManager(auroraClient)
-		if err := viper.UnmarshalKey("status", &thingManager.Status); err != nil {
-			log.WithError(err).Error("Could not parse status configuration.")
+		auroraClient, devices := openDevices()
+
+		var err error
+		background := colorful.Color{}
+		onstart := viper.GetString("onstart")
+		if onstart != "" {
+			if background, err = colorful.Hex(onstart); err != nil {
+				log.WithError(err).Error("Invalid onstart color.")
+				os.Exit(1)
+			}
+		}
+		compositor := auroraops.NewCompositor(auroraClient, background, auroraops.CompositorConfig{
+			FrameRate:  viper.GetInt("compositor.fps"),
+			Brightness: viper.GetFloat64("compositor.brightness"),
+			Gamma:      viper.GetFloat64("compositor.gamma"),
+		})
+		// A device that comes back online has lost what it was showing.
+		auroraClient.Reconnected = compositor.Invalidate
+		if err = auroraClient.Start(); err != nil {
+			log.WithError(err).Error("Could not connect to aurora.")
 			os.Exit(1)
 		}
-		if err := viper.UnmarshalKey("things", &thingManager.Things); err != nil {
+
+		thingManager := auroraops.NewThingManager(auroraClient, compositor)
+		status, things, err := loadThingConfig(viper.GetViper(), devices)
+		if err != nil {
 			log.WithError(err).Error("Could not parse status configuration.")
 			os.Exit(1)
 		}
+		thingManager.Devices = devices
+		thingManager.Status = status
+		thingManager.Things = things
 
 		if err := thingManager.Init(); err != nil {
 			log.WithError(err).Error("Invalid thing configuration.")
 			os.Exit(1)
 		}
 
-		onstart := viper.GetString("onstart")
+		schedules, err := auroraops.ParseScheduleConfig(viper.Get("schedules"))
+		if err != nil {
+			log.WithError(err).Error("Could not parse schedule configuration.")
+			os.Exit(1)
+		}
+		scheduler, err := auroraops.NewScheduler(auroraClient, thingManager, compositor, schedules)
+		if err != nil {
+			log.WithError(err).Error("Invalid schedule configuration.")
+			os.Exit(1)
+		}
+
+		layoutWatcher, err := auroraops.NewLayoutWatcher(auroraClient, thingManager, viper.GetString("layout.snapshot"), time.Duration(viper.GetInt64("layout.interval"))*time.Second, viper.GetBool("layout.remap"))
+		if err != nil {
+			log.WithError(err).Error("Could not load panel layout snapshot.")
+			os.Exit(1)
+		}
+
 		log.WithField("color", onstart).Info("Clearing panels")
 		if onstart != "" {
 			if err := auroraops.ClearPanels(auroraClient, onstart); err != nil {
@@ -211,11 +179,32 @@ var serverCmd = &cobra.Command{
 
 		// pretty.Println(thingManager)
 
-		if err = thingManager.StartAll(auroraClient); err != nil {
+		if err = compositor.Start(); err != nil {
+			log.WithError(err).Error("Could not start compositor.")
+			os.Exit(1)
+		}
+
+		if err = thingManager.StartAll(); err != nil {
 			log.WithError(err).Error("Could not start things.")
 			os.Exit(1)
 		}
 
+		if err = scheduler.Start(); err != nil {
+			log.WithError(err).Error("Could not start scheduler.")
+			os.Exit(1)
+		}
+
+		if err = layoutWatcher.Start(); err != nil {
+			log.WithError(err).Error("Could not start layout watcher.")
+			os.Exit(1)
+		}
+
+		controlServer := auroraops.NewControlServer(viper.GetString("control.address"), thingManager, auroraClient)
+		if err = controlServer.Start(); err != nil {
+			log.WithError(err).Error("Could not start control server.")
+			os.Exit(1)
+		}
+
 		stop := make(chan struct{})
 		var wg sync.WaitGroup
 		wg.Add(2)
@@ -240,16 +229,94 @@ var serverCmd = &cobra.Command{
 			wg.Done()
 		}()
 
+		// The configuration is read into its own viper and checked there, so
+		// that a rejected configuration never replaces the running one.
+		reload := func() {
+			next, data, err := readConfig(viper.ConfigFileUsed())
+			if err != nil {
+				log.WithError(err).Error("Could not read configuration, keeping the running one.")
+				return
+			}
+			if !checkSchema(next) {
+				log.Error("Configuration does not match the schema, keeping the running one.")
+				return
+			}
+			status, things, err := loadThingConfig(next, devices)
+			if err != nil {
+				log.WithError(err).Error("Could not parse configuration, keeping the running one.")
+				return
+			}
+			// Panels may have been rearranged since the layout was last read.
+			if err := thingManager.RefreshLayout(); err != nil {
+				log.WithError(err).Warn("Could not refresh panel layout.")
+			}
+			if err := thingManager.Reload(status, things); err != nil {
+				log.WithError(err).Error("Invalid configuration, keeping the running one.")
+				return
+			}
+			if !reflect.DeepEqual(next.Get("devices"), viper.Get("devices")) {
+				log.Warn("Devices changed, restart the server to use them.")
+			}
+			if err := viper.ReadConfig(bytes.NewReader(data)); err != nil {
+				log.WithError(err).Error("Could not replace the running configuration.")
+				return
+			}
+			log.Info("Configuration reloaded. Changes to devices, schedules, the compositor and the control server are not reloaded and need a restart.")
+		}
+		// Changes to the file and SIGHUP are handled by one goroutine, since
+		// viper cannot be read and reloaded from several at once.
+		changes := make(chan fsnotify.Event)
+		if file := viper.ConfigFileUsed(); file != "" {
+			if err := watchConfig(file, changes, stop); err != nil {
+				log.WithError(err).Warn("Could not watch configuration file, reload it with SIGHUP.")
+			}
+		}
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		go func() {
+			for {
+				select {
+				case e := <-changes:
+					log.WithField("file", e.Name).Info("Configuration changed.")
+					reload()
+				case <-hup:
+					log.Info("Received SIGHUP, reloading configuration.")
+					reload()
+				case <-stop:
+					return
+				}
+			}
+		}()
+
 		c := make(chan os.Signal, 1)
 		signal.Notify(c, os.Interrupt, os.Kill)
 		<-c
+		signal.Stop(hup)
 		close(stop)
 		wg.Wait()
 
-		if err = thingManager.StopAll(auroraClient); err != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err = controlServer.Stop(ctx); err != nil {
+			log.WithError(err).Error("Could not stop control server.")
+		}
+
+		if err = layoutWatcher.Stop(ctx); err != nil {
+			log.WithError(err).Error("Could not stop layout watcher.")
+		}
+
+		if err = scheduler.Stop(ctx); err != nil {
+			log.WithError(err).Error("Could not stop scheduler.")
+		}
+
+		if err = thingManager.StopAll(); err != nil {
 			log.WithError(err).Error("Could not stop things.")
 		}
 
+		if err = compositor.Stop(ctx); err != nil {
+			log.WithError(err).Error("Could not stop compositor.")
+		}
+
 		time.Sleep(2 * time.Second)
 
 		if err = auroraClient.Stop(); err != nil {
@@ -261,24 +328,140 @@ var serverCmd = &cobra.Command{
 
 func init() {
 	log.SetLevel(log.InfoLevel)
-	RootCmd.AddCommand(initCmd)
 	RootCmd.AddCommand(infoCmd)
 	RootCmd.AddCommand(serverCmd)
 
 	serverCmd.Flags().StringVar(&cfgFile, "config", "", "config file (default is ./auroraops.yaml)")
 
-	viper.SetDefault("status.location", "http://localhost:8080/")
-	viper.SetDefault("status.interval", 3)
-	viper.SetDefault("validate.thing", true)
-	viper.SetDefault("validate.status", true)
-
-	viper.AutomaticEnv()
-	viper.SetEnvPrefix("AURORAOPS")
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	configDefaults(viper.GetViper())
 
 	cobra.OnInitialize(initConfig)
 }
 
+// configDefaults sets the defaults and environment variables of a
+// configuration.
+func configDefaults(v *viper.Viper) {
+	v.SetDefault("status.location", "http://localhost:8080/")
+	v.SetDefault("status.interval", 3)
+	v.SetDefault("validate.thing", true)
+	v.SetDefault("validate.status", true)
+	v.SetDefault("compositor.fps", 20)
+	v.SetDefault("compositor.brightness", 1.0)
+	v.SetDefault("compositor.gamma", 1.0)
+	v.SetDefault("control.address", "127.0.0.1:16080")
+	v.SetDefault("layout.interval", 60)
+	v.SetDefault("layout.remap", false)
+
+	v.AutomaticEnv()
+	v.SetEnvPrefix("AURORAOPS")
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+}
+
+// readConfig reads a configuration file into a new viper, returning the
+// contents it was read from.
+func readConfig(file string) (*viper.Viper, []byte, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	v := viper.New()
+	configDefaults(v)
+	v.SetConfigFile(file)
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, nil, err
+	}
+	return v, data, nil
+}
+
+// loadDevices reads the auroras to drive, which are either the devices
+// section of the configuration or the single aurora of the panel section. It
+// returns no devices when neither is configured.
+func loadDevices() ([]auroraops.DeviceConfig, error) {
+	if viper.IsSet("devices") {
+		return auroraops.ParseDeviceConfig(viper.Get("devices"))
+	}
+	if viper.GetString("panel.url") == "" {
+		return []auroraops.DeviceConfig{}, nil
+	}
+	return []auroraops.DeviceConfig{{
+		URL: viper.GetString("panel.url"),
+		Key: viper.GetString("panel.key"),
+	}}, nil
+}
+
+// openDevices creates a device set for the configured auroras.
+func openDevices() (*auroraops.DeviceSet, []auroraops.DeviceConfig) {
+	devices, err := loadDevices()
+	if err != nil {
+		log.WithError(err).Error("Could not parse device configuration.")
+		os.Exit(1)
+	}
+	deviceSet, err := auroraops.NewDeviceSet(devices)
+	if err != nil {
+		log.WithError(err).Error("Could not create aurora client.")
+		os.Exit(1)
+	}
+	return deviceSet, devices
+}
+
+// loadThingConfig reads the status and thing configuration, replacing the
+// regions things select with their selectors.
+func loadThingConfig(v *viper.Viper, devices []auroraops.DeviceConfig) (map[string]auroraops.StatusConfigSet, map[string]auroraops.ThingConfigSet, error) {
+	status, err := auroraops.ParseStatusConfig(v.Get("status"))
+	if err != nil {
+		return nil, nil, err
+	}
+	things, err := auroraops.ParseThingConfig(v.Get("things"), devices)
+	if err != nil {
+		return nil, nil, err
+	}
+	regions, err := auroraops.ParseRegionConfig(v.Get("regions"))
+	if err != nil {
+		return nil, nil, err
+	}
+	things, err = auroraops.ApplyRegions(things, regions)
+	if err != nil {
+		return nil, nil, err
+	}
+	return status, things, nil
+}
+
+// watchConfig sends an event whenever the configuration file is written or
+// replaced. The directory of the file is watched, so that editors that replace
+// the file rather than writing to it are noticed too.
+func watchConfig(file string, changes chan<- fsnotify.Event, stop <-chan struct{}) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	file = filepath.Clean(file)
+	if err := watcher.Add(filepath.Dir(file)); err != nil {
+		watcher.Close()
+		return err
+	}
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case e := <-watcher.Events:
+				if filepath.Clean(e.Name) != file || e.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+					continue
+				}
+				select {
+				case changes <- e:
+				case <-stop:
+					return
+				}
+			case err := <-watcher.Errors:
+				log.WithError(err).Warn("Could not watch configuration file.")
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
 func initConfig() {
 	if cfgFile != "" {
 		viper.SetConfigFile(cfgFile)
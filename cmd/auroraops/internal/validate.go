@@ -0,0 +1,147 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/ngerakines/auroraops"
+	"github.com/ngerakines/auroraops/client"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	validateLayout  string
+	validateOffline bool
+	validateRemote  bool
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the configuration without running it.",
+	Run: func(cmd *cobra.Command, args []string) {
+		// Checking creates every action, which would otherwise log each one.
+		log.SetLevel(log.WarnLevel)
+
+		check := auroraops.ConfigCheck{
+			Devices:   viper.Get("devices"),
+			Status:    viper.Get("status"),
+			Things:    viper.Get("things"),
+			Regions:   viper.Get("regions"),
+			Schedules: viper.Get("schedules"),
+			OnStart:   viper.GetString("onstart"),
+			OnStop:    viper.GetString("onstop"),
+		}
+
+		layout, err := loadLayout(validateLayout, validateOffline)
+		if err != nil {
+			log.WithError(err).Error("Could not load panel layout.")
+			os.Exit(1)
+		}
+		if layout == nil {
+			fmt.Println("Skipping panel checks, no layout is available.")
+		} else {
+			check.Layout = layout.Panels
+		}
+
+		if validateRemote {
+			remote, err := fetchRemoteStatus()
+			if err != nil {
+				log.WithError(err).Error("Could not fetch remote configuration.")
+				os.Exit(1)
+			}
+			check.Remote = remote
+		}
+
+		diagnostics := auroraops.CheckConfig(check)
+		// Sections that CheckConfig does not cover are checked against the
+		// schema alone.
+		for _, diagnostic := range auroraops.CheckSchema(viper.AllSettings()) {
+			if len(diagnostic.Path) > 0 {
+				switch diagnostic.Path[0] {
+				case "devices", "status", "things", "regions", "schedules", "onstart", "onstop":
+					continue
+				}
+			}
+			diagnostics = append(diagnostics, diagnostic)
+		}
+		if file := viper.ConfigFileUsed(); file != "" {
+			if err := auroraops.LocateDiagnostics(diagnostics, file); err != nil {
+				log.WithError(err).Warn("Could not locate problems in the configuration file.")
+			}
+		}
+		sort.SliceStable(diagnostics, func(i, j int) bool {
+			return diagnostics[i].Line < diagnostics[j].Line
+		})
+
+		errorCount, warningCount := 0, 0
+		for _, diagnostic := range diagnostics {
+			fmt.Println(diagnostic)
+			if diagnostic.Severity == auroraops.SeverityError {
+				errorCount = errorCount + 1
+			} else {
+				warningCount = warningCount + 1
+			}
+		}
+		fmt.Printf("%d errors, %d warnings\n", errorCount, warningCount)
+		if errorCount > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// loadLayout reads the panel layout from a layout file, or from the aurora
+// unless offline. It returns nil when neither is available.
+func loadLayout(file string, offline bool) (*client.HardwareInfo, error) {
+	if file != "" {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		return client.ParseHardwareInfo(data)
+	}
+	if offline {
+		return nil, nil
+	}
+	devices, err := loadDevices()
+	if err != nil || len(devices) == 0 {
+		// Problems with the devices are reported by the configuration check.
+		return nil, nil
+	}
+	deviceSet, err := auroraops.NewDeviceSet(devices)
+	if err != nil {
+		return nil, err
+	}
+	return deviceSet.GetInfo()
+}
+
+func fetchRemoteStatus() (auroraops.StatusMap, error) {
+	httpClient := &http.Client{
+		Timeout: time.Second * 10,
+	}
+	response, err := httpClient.Get(viper.GetString("status.location"))
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	remote := auroraops.StatusMap{}
+	if err := json.NewDecoder(response.Body).Decode(&remote); err != nil {
+		return nil, err
+	}
+	return remote, nil
+}
+
+func init() {
+	RootCmd.AddCommand(validateCmd)
+
+	validateCmd.Flags().StringVar(&cfgFile, "config", "", "config file (default is ./auroraops.yaml)")
+	validateCmd.Flags().StringVar(&validateLayout, "layout", "", "panel layout file to check panels against instead of the aurora")
+	validateCmd.Flags().BoolVar(&validateOffline, "offline", false, "do not contact the aurora")
+	validateCmd.Flags().BoolVar(&validateRemote, "remote", false, "count statuses the remote configuration reports as referenced")
+}
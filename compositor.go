@@ -0,0 +1,262 @@
+package auroraops
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	colorful "github.com/lucasb-eyer/go-colorful"
+	"github.com/ngerakines/auroraops/client"
+	log "github.com/sirupsen/logrus"
+	tomb "gopkg.in/tomb.v2"
+)
+
+// Canvas is the surface an action draws on. Colors written to a canvas are
+// held in a frame buffer and composed by the compositor on its next frame.
+type Canvas interface {
+	SetPanelColor(panel int, color colorful.Color)
+	ClearPanel(panel int)
+}
+
+// Compositor owns the frame buffer of the aurora. Actions draw into layers,
+// and on every frame the compositor blends the layers of each panel from the
+// lowest priority to the highest, applies global brightness and gamma, and
+// sends the panels that changed since the last frame.
+type Compositor struct {
+	auroraClient client.AuroraClient
+	background   colorful.Color
+	layers       []*layer
+	panels       map[int]bool
+	sent         map[int][3]uint8
+
+	frameRate  int
+	brightness float64
+	gamma      float64
+
+	t  tomb.Tomb
+	mu sync.Mutex
+}
+
+type layer struct {
+	priority   int
+	opacity    float64
+	colors     map[int]colorful.Color
+	transition *transition
+	compositor *Compositor
+}
+
+// CompositorConfig sets how often frames are sent and how colors are
+// corrected before they are sent. Values that are not set default to 20
+// frames per second, full brightness and a gamma of 1.
+type CompositorConfig struct {
+	FrameRate  int
+	Brightness float64
+	Gamma      float64
+}
+
+func NewCompositor(auroraClient client.AuroraClient, background colorful.Color, config CompositorConfig) *Compositor {
+	if config.FrameRate <= 0 {
+		config.FrameRate = 20
+	}
+	if config.Brightness <= 0 {
+		config.Brightness = 1
+	}
+	if config.Gamma <= 0 {
+		config.Gamma = 1
+	}
+	return &Compositor{
+		auroraClient: auroraClient,
+		background:   background,
+		layers:       []*layer{},
+		panels:       make(map[int]bool),
+		sent:         make(map[int][3]uint8),
+		frameRate:    config.FrameRate,
+		brightness:   math.Min(1, config.Brightness),
+		gamma:        config.Gamma,
+	}
+}
+
+func (c *Compositor) Start() error {
+	c.t.Go(c.loop)
+	return nil
+}
+
+// Stop ends the render loop after sending one last frame.
+func (c *Compositor) Stop(ctx context.Context) error {
+	log.WithField("component", "compositor").Info("Stopping")
+	c.t.Kill(nil)
+	if err := c.t.Wait(); err != nil {
+		return err
+	}
+	return c.Render()
+}
+
+func (c *Compositor) loop() error {
+	ticker := time.NewTicker(time.Second / time.Duration(c.frameRate))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.Render(); err != nil {
+				log.WithError(err).Error("Could not render frame.")
+			}
+		case <-c.t.Dying():
+			return nil
+		}
+	}
+}
+
+// SetBrightness scales every color sent to the aurora. Values are between 0
+// and 1.
+func (c *Compositor) SetBrightness(brightness float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.brightness = math.Max(0, math.Min(1, brightness))
+}
+
+// Invalidate forgets what was sent to the aurora so that every panel is sent
+// again on the next frame, such as after the aurora was turned back on.
+func (c *Compositor) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = make(map[int][3]uint8)
+}
+
+// Render composes the current frame and sends the panels that changed.
+func (c *Compositor) Render() error {
+	commands := []*client.PanelColorCommand{}
+	for panel, rgb := range c.renderFrame(time.Now()) {
+		commands = append(commands, &client.PanelColorCommand{
+			ID: panel,
+			R:  rgb[0],
+			G:  rgb[1],
+			B:  rgb[2],
+		})
+	}
+	if len(commands) == 0 {
+		return nil
+	}
+	return c.auroraClient.SetPanelColors(commands)
+}
+
+// renderFrame composes every known panel at the given time and returns the
+// ones whose output differs from what was last sent.
+func (c *Compositor) renderFrame(now time.Time) map[int][3]uint8 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	changed := make(map[int][3]uint8)
+	for panel := range c.panels {
+		rgb := c.output(c.compose(panel, now))
+		if sent, ok := c.sent[panel]; ok && sent == rgb {
+			continue
+		}
+		c.sent[panel] = rgb
+		changed[panel] = rgb
+	}
+	return changed
+}
+
+func (c *Compositor) compose(panel int, now time.Time) colorful.Color {
+	color := c.background
+	for _, l := range c.layers {
+		color = l.compose(panel, color, now)
+	}
+	return color
+}
+
+func (c *Compositor) output(color colorful.Color) [3]uint8 {
+	channel := func(v float64) uint8 {
+		v = math.Max(0, math.Min(1, v))
+		if c.gamma > 0 {
+			v = math.Pow(v, c.gamma)
+		}
+		return uint8(v*c.brightness*255.0 + 0.5)
+	}
+	return [3]uint8{channel(color.R), channel(color.G), channel(color.B)}
+}
+
+// newLayer creates a layer. Layers with the same priority are stacked in the
+// order they are created.
+func (c *Compositor) newLayer(priority int, opacity float64) *layer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if opacity <= 0 || opacity > 1 {
+		opacity = 1
+	}
+	l := &layer{
+		priority:   priority,
+		opacity:    opacity,
+		colors:     make(map[int]colorful.Color),
+		compositor: c,
+	}
+	c.layers = append(c.layers, l)
+	sort.SliceStable(c.layers, func(i, j int) bool {
+		return c.layers[i].priority < c.layers[j].priority
+	})
+	return l
+}
+
+func (c *Compositor) removeLayer(l *layer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, existing := range c.layers {
+		if existing == l {
+			c.layers = append(c.layers[:i], c.layers[i+1:]...)
+			return
+		}
+	}
+}
+
+// blend draws the layer's color for a panel over the color below it.
+func (l *layer) blend(panel int, below colorful.Color) colorful.Color {
+	if color, ok := l.colors[panel]; ok {
+		return below.BlendRgb(color, l.opacity)
+	}
+	return below
+}
+
+// compose draws the layer over the color below it, mixing in the layer it is
+// replacing while a transition is running.
+func (l *layer) compose(panel int, below colorful.Color, now time.Time) colorful.Color {
+	incoming := l.blend(panel, below)
+	if l.transition == nil {
+		return incoming
+	}
+	p := l.transition.progress(now)
+	if p >= 1 {
+		return incoming
+	}
+	outgoing := l.transition.from.blend(panel, below)
+	return l.transition.mix(panel, outgoing, incoming, p)
+}
+
+// setTransition starts mixing the layer with the one it replaces, or stops
+// when given nil.
+func (l *layer) setTransition(t *transition) {
+	c := l.compositor
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	l.transition = t
+}
+
+func (l *layer) SetPanelColor(panel int, color colorful.Color) {
+	c := l.compositor
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	l.colors[panel] = color
+	c.panels[panel] = true
+}
+
+func (l *layer) ClearPanel(panel int) {
+	c := l.compositor
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(l.colors, panel)
+}
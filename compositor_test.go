@@ -0,0 +1,339 @@
+package auroraops
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	colorful "github.com/lucasb-eyer/go-colorful"
+	"github.com/ngerakines/auroraops/client"
+)
+
+// fakeAuroraClient reports its panels as the layout and records the panel
+// colors it is sent.
+type fakeAuroraClient struct {
+	panels []*client.Panel
+	sent   []map[int][3]uint8
+}
+
+func (f *fakeAuroraClient) Authorize() (string, error) { return "", nil }
+
+func (f *fakeAuroraClient) GetInfo() (*client.HardwareInfo, error) {
+	return &client.HardwareInfo{Panels: f.panels}, nil
+}
+
+func (f *fakeAuroraClient) SetPanelColor(panel, r, g, b byte) error {
+	return f.SetPanelColors([]*client.PanelColorCommand{{ID: int(panel), R: r, G: g, B: b}})
+}
+
+func (f *fakeAuroraClient) SetPanelColors(commands []*client.PanelColorCommand) error {
+	frame := make(map[int][3]uint8)
+	for _, command := range commands {
+		frame[command.ID] = [3]uint8{command.R, command.G, command.B}
+	}
+	f.sent = append(f.sent, frame)
+	return nil
+}
+
+func (f *fakeAuroraClient) SetPower(on bool) error { return nil }
+
+func (f *fakeAuroraClient) SetBrightness(brightness int) error { return nil }
+
+func (f *fakeAuroraClient) TouchEvents(stop <-chan struct{}) (<-chan int, error) { return nil, nil }
+
+func (f *fakeAuroraClient) Stop() error { return nil }
+
+// newTestCompositor creates a compositor on a black background with the
+// default brightness and gamma.
+func newTestCompositor() (*Compositor, *fakeAuroraClient) {
+	fake := &fakeAuroraClient{}
+	return NewCompositor(fake, colorful.Color{}, CompositorConfig{}), fake
+}
+
+var (
+	black = colorful.Color{}
+	white = colorful.Color{R: 1, G: 1, B: 1}
+	red   = colorful.Color{R: 1}
+	blue  = colorful.Color{B: 1}
+)
+
+func TestNewCompositor(t *testing.T) {
+	tests := []struct {
+		name           string
+		config         CompositorConfig
+		wantFrameRate  int
+		wantBrightness float64
+		wantGamma      float64
+	}{
+		{
+			name:           "defaults what is not set",
+			config:         CompositorConfig{},
+			wantFrameRate:  20,
+			wantBrightness: 1,
+			wantGamma:      1,
+		},
+		{
+			name:           "keeps what is set",
+			config:         CompositorConfig{FrameRate: 30, Brightness: 0.5, Gamma: 2.2},
+			wantFrameRate:  30,
+			wantBrightness: 0.5,
+			wantGamma:      2.2,
+		},
+		{
+			name:           "caps brightness",
+			config:         CompositorConfig{Brightness: 3},
+			wantFrameRate:  20,
+			wantBrightness: 1,
+			wantGamma:      1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCompositor(&fakeAuroraClient{}, black, tt.config)
+			if c.frameRate != tt.wantFrameRate || c.brightness != tt.wantBrightness || c.gamma != tt.wantGamma {
+				t.Errorf("NewCompositor() = fps %d, brightness %v, gamma %v, want fps %d, brightness %v, gamma %v",
+					c.frameRate, c.brightness, c.gamma, tt.wantFrameRate, tt.wantBrightness, tt.wantGamma)
+			}
+		})
+	}
+}
+
+func TestCompositorLayers(t *testing.T) {
+	type layerSpec struct {
+		priority int
+		opacity  float64
+		color    *colorful.Color
+	}
+	tests := []struct {
+		name   string
+		layers []layerSpec
+		want   [3]uint8
+	}{
+		{
+			name:   "shows the background under no color",
+			layers: []layerSpec{{priority: 0, opacity: 1}},
+			want:   [3]uint8{0, 0, 0},
+		},
+		{
+			name:   "draws higher priorities on top",
+			layers: []layerSpec{{priority: 10, opacity: 1, color: &red}, {priority: 0, opacity: 1, color: &blue}},
+			want:   [3]uint8{255, 0, 0},
+		},
+		{
+			name:   "stacks equal priorities in the order they are created",
+			layers: []layerSpec{{priority: 0, opacity: 1, color: &red}, {priority: 0, opacity: 1, color: &blue}},
+			want:   [3]uint8{0, 0, 255},
+		},
+		{
+			name:   "blends by opacity",
+			layers: []layerSpec{{priority: 0, opacity: 1, color: &red}, {priority: 1, opacity: 0.5, color: &blue}},
+			want:   [3]uint8{128, 0, 128},
+		},
+		{
+			name:   "treats an unset opacity as opaque",
+			layers: []layerSpec{{priority: 0, opacity: 1, color: &red}, {priority: 1, opacity: 0, color: &blue}},
+			want:   [3]uint8{0, 0, 255},
+		},
+		{
+			name:   "shows lower layers through panels a layer leaves clear",
+			layers: []layerSpec{{priority: 0, opacity: 1, color: &red}, {priority: 1, opacity: 1}},
+			want:   [3]uint8{255, 0, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestCompositor()
+			for _, spec := range tt.layers {
+				l := c.newLayer(spec.priority, spec.opacity)
+				if spec.color != nil {
+					l.SetPanelColor(1, *spec.color)
+				} else {
+					l.SetPanelColor(1, white)
+					l.ClearPanel(1)
+				}
+			}
+			if got := c.renderFrame(time.Now())[1]; got != tt.want {
+				t.Errorf("panel 1 = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompositorOutput(t *testing.T) {
+	tests := []struct {
+		name       string
+		brightness float64
+		gamma      float64
+		color      colorful.Color
+		want       [3]uint8
+	}{
+		{
+			name:       "sends colors as they are",
+			brightness: 1,
+			color:      colorful.Color{R: 1, G: 0.5, B: 0},
+			want:       [3]uint8{255, 128, 0},
+		},
+		{
+			name:       "scales by brightness",
+			brightness: 0.5,
+			color:      white,
+			want:       [3]uint8{128, 128, 128},
+		},
+		{
+			name:       "applies gamma before brightness",
+			brightness: 0.5,
+			gamma:      2,
+			color:      colorful.Color{R: 1, G: 0.5, B: 0},
+			want:       [3]uint8{128, 32, 0},
+		},
+		{
+			name:       "clamps colors out of range",
+			brightness: 1,
+			color:      colorful.Color{R: 1.5, G: -0.5, B: 0.5},
+			want:       [3]uint8{255, 0, 128},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestCompositor()
+			c.brightness = tt.brightness
+			c.gamma = tt.gamma
+			if got := c.output(tt.color); got != tt.want {
+				t.Errorf("output(%v) = %v, want %v", tt.color, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompositorRender(t *testing.T) {
+	c, fake := newTestCompositor()
+	l := c.newLayer(0, 1)
+	l.SetPanelColor(1, red)
+	l.SetPanelColor(2, blue)
+
+	render := func() map[int][3]uint8 {
+		t.Helper()
+		before := len(fake.sent)
+		if err := c.Render(); err != nil {
+			t.Fatal(err)
+		}
+		if len(fake.sent) == before {
+			return nil
+		}
+		return fake.sent[len(fake.sent)-1]
+	}
+
+	if got, want := render(), map[int][3]uint8{1: {255, 0, 0}, 2: {0, 0, 255}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("first frame = %v, want %v", got, want)
+	}
+	if got := render(); got != nil {
+		t.Errorf("unchanged frame sent %v", got)
+	}
+	l.SetPanelColor(2, white)
+	if got, want := render(), map[int][3]uint8{2: {255, 255, 255}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("changed frame = %v, want %v", got, want)
+	}
+	c.Invalidate()
+	if got, want := render(), map[int][3]uint8{1: {255, 0, 0}, 2: {255, 255, 255}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("invalidated frame = %v, want %v", got, want)
+	}
+}
+
+func TestTransitionMix(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   TransitionConfig
+		panel    int
+		progress float64
+		want     colorful.Color
+	}{
+		{
+			name:     "crossfade halfway",
+			config:   TransitionConfig{Type: "crossfade", Duration: time.Second},
+			panel:    1,
+			progress: 0.5,
+			want:     colorful.Color{R: 0.5, G: 0.5, B: 0.5},
+		},
+		{
+			name:     "wipe fades the first panel first",
+			config:   TransitionConfig{Type: "wipe", Duration: time.Second},
+			panel:    1,
+			progress: 0.25,
+			want:     colorful.Color{R: 0.5, G: 0.5, B: 0.5},
+		},
+		{
+			name:     "wipe holds later panels",
+			config:   TransitionConfig{Type: "wipe", Duration: time.Second},
+			panel:    2,
+			progress: 0.25,
+			want:     black,
+		},
+		{
+			name:     "wipe finishes the last panel at the end",
+			config:   TransitionConfig{Type: "wipe", Duration: time.Second},
+			panel:    2,
+			progress: 1,
+			want:     white,
+		},
+		{
+			name:     "flash rises to its color",
+			config:   TransitionConfig{Type: "flash", Duration: time.Second, Color: "#ff0000"},
+			panel:    1,
+			progress: 0.125,
+			want:     colorful.Color{R: 0.5},
+		},
+		{
+			name:     "flash peaks at a quarter",
+			config:   TransitionConfig{Type: "flash", Duration: time.Second, Color: "#ff0000"},
+			panel:    1,
+			progress: 0.25,
+			want:     red,
+		},
+		{
+			name:     "flash fades into the incoming color",
+			config:   TransitionConfig{Type: "flash", Duration: time.Second, Color: "#ff0000"},
+			panel:    1,
+			progress: 1,
+			want:     white,
+		},
+		{
+			name:     "flash defaults to white",
+			config:   TransitionConfig{Type: "flash", Duration: time.Second},
+			panel:    1,
+			progress: 0.125,
+			want:     colorful.Color{R: 0.5, G: 0.5, B: 0.5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, err := newTransition(tt.config, nil, nil, []int{1, 2})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tr.mix(tt.panel, black, white, tt.progress); !got.AlmostEqualRgb(tt.want) {
+				t.Errorf("mix(%d, %v) = %v, want %v", tt.panel, tt.progress, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompositorTransition(t *testing.T) {
+	c, _ := newTestCompositor()
+	from := c.newLayer(0, 1)
+	from.SetPanelColor(1, black)
+	to := c.newLayer(0, 1)
+	to.SetPanelColor(1, white)
+	tr, err := newTransition(TransitionConfig{Type: "crossfade", Duration: time.Second}, from, nil, []int{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.removeLayer(from)
+	to.setTransition(tr)
+
+	if got, want := c.renderFrame(tr.start.Add(500 * time.Millisecond))[1], [3]uint8{128, 128, 128}; got != want {
+		t.Errorf("halfway = %v, want %v", got, want)
+	}
+	if got, want := c.renderFrame(tr.start.Add(2 * time.Second))[1], [3]uint8{255, 255, 255}; got != want {
+		t.Errorf("after = %v, want %v", got, want)
+	}
+}
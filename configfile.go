@@ -0,0 +1,225 @@
+package auroraops
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// UpdateThings rewrites the things section of a YAML or JSON configuration
+// file. Things are first renamed from the keys of renamed to its values, and
+// then each thing in panels is given those panels, adding things that are not
+// in the file. A thing left with no panels is removed unless it has other
+// settings. Panels of devices other than the first are written as references
+// such as "hall:13". The rest of the file, including comments in YAML, is kept.
+func UpdateThings(data []byte, panels map[string][]int, renamed map[string]string, devices []DeviceConfig) ([]byte, error) {
+	document, err := parseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	root := document.Content[0]
+
+	things := mappingValue(root, "things")
+	if things == nil || things.Kind != yaml.MappingNode {
+		things = &yaml.Node{Kind: yaml.MappingNode}
+		setMappingValue(root, "things", things)
+	}
+
+	// Names are matched regardless of case, since the configuration is read
+	// with lowercased keys.
+	for i := 0; i+1 < len(things.Content); i += 2 {
+		for from, to := range renamed {
+			if strings.EqualFold(things.Content[i].Value, from) {
+				things.Content[i].Value = to
+				break
+			}
+		}
+	}
+
+	names := make([]string, 0, len(panels))
+	for name := range panels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		list := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for _, panel := range panels[name] {
+			ref := FormatPanelRef(devices, panel)
+			if ref == strconv.Itoa(panel) {
+				list.Content = append(list.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: ref})
+			} else {
+				list.Content = append(list.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: ref})
+			}
+		}
+
+		thing := mappingValue(things, name)
+		if thing == nil || thing.Kind != yaml.MappingNode {
+			if len(list.Content) == 0 {
+				continue
+			}
+			thing = &yaml.Node{Kind: yaml.MappingNode}
+			setMappingValue(things, name, thing)
+		}
+		if len(list.Content) == 0 && (len(thing.Content) == 0 || (len(thing.Content) == 2 && strings.EqualFold(thing.Content[0].Value, "panels"))) {
+			removeMappingValue(things, name)
+			continue
+		}
+		setMappingValue(thing, "panels", list)
+	}
+
+	return composeDocument(document, data)
+}
+
+// UpdateDevices sets the auroras of a YAML or JSON configuration file. A single
+// device is written to the panel section unless the file already lists
+// devices, and several devices are written to the devices section, which
+// replaces the panel section. Devices are matched with those in the file by URL
+// or name, keeping their other settings such as offset. The rest of the file,
+// including comments in YAML, is kept.
+func UpdateDevices(data []byte, devices []DeviceConfig) ([]byte, error) {
+	document, err := parseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	root := document.Content[0]
+	str := func(value string) *yaml.Node {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	}
+
+	existing := mappingValue(root, "devices")
+	if existing == nil || existing.Kind != yaml.SequenceNode {
+		existing = nil
+	}
+	if len(devices) == 1 && existing == nil {
+		panel := mappingValue(root, "panel")
+		if panel == nil || panel.Kind != yaml.MappingNode {
+			panel = &yaml.Node{Kind: yaml.MappingNode}
+			setMappingValue(root, "panel", panel)
+		}
+		setMappingValue(panel, "url", str(devices[0].URL))
+		setMappingValue(panel, "key", str(devices[0].Key))
+		return composeDocument(document, data)
+	}
+
+	list := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, device := range devices {
+		var entry *yaml.Node
+		if existing != nil {
+			for _, node := range existing.Content {
+				if node.Kind != yaml.MappingNode {
+					continue
+				}
+				url, name := mappingValue(node, "url"), mappingValue(node, "name")
+				if (url != nil && url.Value == device.URL) || (name != nil && device.Name != "" && name.Value == device.Name) {
+					entry = node
+					break
+				}
+			}
+		}
+		if entry == nil {
+			entry = &yaml.Node{Kind: yaml.MappingNode}
+		}
+		if device.Name != "" {
+			setMappingValue(entry, "name", str(device.Name))
+		}
+		setMappingValue(entry, "url", str(device.URL))
+		setMappingValue(entry, "key", str(device.Key))
+		list.Content = append(list.Content, entry)
+	}
+	if existing == nil {
+		// The devices section takes the place of the panel section, along
+		// with its comments.
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if strings.EqualFold(root.Content[i].Value, "panel") {
+				root.Content[i].Value = "devices"
+				root.Content[i+1] = list
+				return composeDocument(document, data)
+			}
+		}
+	}
+	setMappingValue(root, "devices", list)
+	removeMappingValue(root, "panel")
+	return composeDocument(document, data)
+}
+
+// parseDocument reads a YAML or JSON configuration file, which may be empty,
+// into a document whose content is a map.
+func parseDocument(data []byte) (*yaml.Node, error) {
+	document := &yaml.Node{}
+	if err := yaml.Unmarshal(data, document); err != nil {
+		return nil, errors.Wrap(err, "could not parse configuration")
+	}
+	if document.Kind != yaml.DocumentNode {
+		document = &yaml.Node{Kind: yaml.DocumentNode}
+	}
+	if len(document.Content) == 0 {
+		document.Content = []*yaml.Node{{Kind: yaml.MappingNode}}
+	}
+	if document.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("configuration is not a map")
+	}
+	return document, nil
+}
+
+// composeDocument writes a document in the format of the file it was read
+// from.
+func composeDocument(document *yaml.Node, data []byte) ([]byte, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		var config interface{}
+		if err := document.Decode(&config); err != nil {
+			return nil, errors.Wrap(err, "could not compose configuration")
+		}
+		out, err := json.MarshalIndent(config, "", "  ")
+		if err != nil {
+			return nil, errors.Wrap(err, "could not compose configuration")
+		}
+		return append(out, '\n'), nil
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(document); err != nil {
+		return nil, errors.Wrap(err, "could not compose configuration")
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, errors.Wrap(err, "could not compose configuration")
+	}
+	return out.Bytes(), nil
+}
+
+// mappingValue returns the value of a key in a YAML map, matching keys
+// regardless of case as the configuration does.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if strings.EqualFold(node.Content[i].Value, key) {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func setMappingValue(node *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if strings.EqualFold(node.Content[i].Value, key) {
+			value.LineComment = node.Content[i+1].LineComment
+			node.Content[i+1] = value
+			return
+		}
+	}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+}
+
+func removeMappingValue(node *yaml.Node, key string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if strings.EqualFold(node.Content[i].Value, key) {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package auroraops
+
+import (
+	"testing"
+)
+
+func TestUpdateThings(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		panels  map[string][]int
+		renamed map[string]string
+		devices []DeviceConfig
+		want    string
+	}{
+		{
+			name:   "keeps comments and other settings",
+			data:   "# wall\nthings:\n  website: # the site\n    panels: [1, 2]\n    priority: 0\n",
+			panels: map[string][]int{"website": {3, 4}},
+			want:   "# wall\nthings:\n  website: # the site\n    panels: [3, 4]\n    priority: 0\n",
+		},
+		{
+			name:   "adds new things",
+			data:   "panel:\n  url: http://aurora\n",
+			panels: map[string][]int{"api": {7}},
+			want:   "panel:\n  url: http://aurora\nthings:\n  api:\n    panels: [7]\n",
+		},
+		{
+			name:   "removes things left without panels",
+			data:   "things:\n  website:\n    panels: [1]\n  api:\n    panels: [2]\n    value: up\n",
+			panels: map[string][]int{"website": {}, "api": {}},
+			want:   "things:\n  api:\n    panels: []\n    value: up\n",
+		},
+		{
+			name:    "renames things regardless of case",
+			data:    "things:\n  Website:\n    panels: [1]\n",
+			panels:  map[string][]int{"site": {1}},
+			renamed: map[string]string{"website": "site"},
+			want:    "things:\n  site:\n    panels: [1]\n",
+		},
+		{
+			name:    "writes panels of other devices as references",
+			data:    "things:\n",
+			panels:  map[string][]int{"website": {1, devicePanelSpan + 2}},
+			devices: []DeviceConfig{{Name: "hall"}, {Name: "lobby"}},
+			want:    "things:\n  website:\n    panels: [1, 'lobby:2']\n",
+		},
+		{
+			name:   "keeps json files json",
+			data:   "{\"things\": {}}",
+			panels: map[string][]int{"website": {1}},
+			want:   "{\n  \"things\": {\n    \"website\": {\n      \"panels\": [\n        1\n      ]\n    }\n  }\n}\n",
+		},
+		{
+			name:   "starts an empty file",
+			data:   "",
+			panels: map[string][]int{"website": {1}},
+			want:   "things:\n  website:\n    panels: [1]\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UpdateThings([]byte(tt.data), tt.panels, tt.renamed, tt.devices)
+			if err != nil {
+				t.Fatalf("UpdateThings() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("UpdateThings() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateDevices(t *testing.T) {
+	hall := DeviceConfig{Name: "hall", URL: "http://hall", Key: "hallkey"}
+	lobby := DeviceConfig{Name: "lobby", URL: "http://lobby", Key: "lobbykey"}
+	tests := []struct {
+		name    string
+		data    string
+		devices []DeviceConfig
+		want    string
+	}{
+		{
+			name:    "starts an empty file with the panel section",
+			data:    "",
+			devices: []DeviceConfig{{URL: "http://hall", Key: "hallkey"}},
+			want:    "panel:\n  url: http://hall\n  key: hallkey\n",
+		},
+		{
+			name:    "updates the panel section, keeping comments",
+			data:    "panel: # the wall\n  url: http://old\n  key: old\nthings:\n  website:\n    panels: [1]\n",
+			devices: []DeviceConfig{{URL: "http://hall", Key: "hallkey"}},
+			want:    "panel: # the wall\n  url: http://hall\n  key: hallkey\nthings:\n  website:\n    panels: [1]\n",
+		},
+		{
+			name:    "replaces the panel section with devices",
+			data:    "panel: # the wall\n  url: http://hall\n  key: old\nthings:\n  website:\n    panels: [1]\n",
+			devices: []DeviceConfig{hall, lobby},
+			want:    "devices: # the wall\n  - name: hall\n    url: http://hall\n    key: hallkey\n  - name: lobby\n    url: http://lobby\n    key: lobbykey\nthings:\n  website:\n    panels: [1]\n",
+		},
+		{
+			name:    "keeps the settings of listed devices",
+			data:    "devices:\n  - name: hall\n    url: http://hall\n    key: old\n  - name: lobby\n    url: http://old\n    offset: [1200, 0]\n",
+			devices: []DeviceConfig{hall, lobby},
+			want:    "devices:\n  - name: hall\n    url: http://hall\n    key: hallkey\n  - name: lobby\n    url: http://lobby\n    offset: [1200, 0]\n    key: lobbykey\n",
+		},
+		{
+			name:    "keeps a single device in the devices section",
+			data:    "devices:\n  - name: hall\n    url: http://hall\n    key: old\n  - name: lobby\n    url: http://lobby\n",
+			devices: []DeviceConfig{hall},
+			want:    "devices:\n  - name: hall\n    url: http://hall\n    key: hallkey\n",
+		},
+		{
+			name:    "keeps json files json",
+			data:    "{\"panel\": {\"url\": \"http://hall\"}}",
+			devices: []DeviceConfig{hall, lobby},
+			want:    "{\n  \"devices\": [\n    {\n      \"key\": \"hallkey\",\n      \"name\": \"hall\",\n      \"url\": \"http://hall\"\n    },\n    {\n      \"key\": \"lobbykey\",\n      \"name\": \"lobby\",\n      \"url\": \"http://lobby\"\n    }\n  ]\n}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UpdateDevices([]byte(tt.data), tt.devices)
+			if err != nil {
+				t.Fatalf("UpdateDevices() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("UpdateDevices() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
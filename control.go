@@ -0,0 +1,214 @@
+package auroraops
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// ControlServer lets the auroraops command talk to a running server over
+// HTTP. Things are acknowledged with a POST to /things/<thing>/ack, and the
+// acknowledgement is cleared with a DELETE to the same path. A GET to /devices
+// reports whether each aurora is online.
+type ControlServer struct {
+	thingManager *ThingManager
+	devices      *DeviceSet
+	server       *http.Server
+}
+
+type acknowledgeRequest struct {
+	For string `json:"for"`
+}
+
+type controlResponse struct {
+	Thing   string         `json:"thing,omitempty"`
+	Until   string         `json:"until,omitempty"`
+	Devices []DeviceHealth `json:"devices,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+func NewControlServer(address string, thingManager *ThingManager, devices *DeviceSet) *ControlServer {
+	s := &ControlServer{
+		thingManager: thingManager,
+		devices:      devices,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/things/", s.handleThing)
+	mux.HandleFunc("/devices", s.handleDevices)
+	s.server = &http.Server{
+		Addr:    address,
+		Handler: mux,
+	}
+	return s
+}
+
+func (s *ControlServer) Start() error {
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return errors.Wrapf(err, "could not listen on %s", s.server.Addr)
+	}
+	log.WithField("address", listener.Addr().String()).Info("control server starting")
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.WithError(err).Error("Control server stopped.")
+		}
+	}()
+	return nil
+}
+
+func (s *ControlServer) Stop(ctx context.Context) error {
+	log.WithField("component", "control").Info("Stopping")
+	return s.server.Shutdown(ctx)
+}
+
+func (s *ControlServer) handleThing(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), "/things/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] != "ack" {
+		writeControlResponse(w, http.StatusNotFound, controlResponse{Error: "not found"})
+		return
+	}
+	thing, err := url.PathUnescape(parts[0])
+	if err != nil {
+		writeControlResponse(w, http.StatusBadRequest, controlResponse{Error: err.Error()})
+		return
+	}
+	if !s.thingManager.HasThing(thing) {
+		writeControlResponse(w, http.StatusNotFound, controlResponse{Thing: thing, Error: "unknown thing"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		req := acknowledgeRequest{}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeControlResponse(w, http.StatusBadRequest, controlResponse{Thing: thing, Error: err.Error()})
+				return
+			}
+		}
+		var duration time.Duration
+		if req.For != "" {
+			var err error
+			if duration, err = time.ParseDuration(req.For); err != nil {
+				writeControlResponse(w, http.StatusBadRequest, controlResponse{Thing: thing, Error: err.Error()})
+				return
+			}
+		}
+		if err := s.thingManager.Acknowledge(thing, duration); err != nil {
+			writeControlResponse(w, http.StatusConflict, controlResponse{Thing: thing, Error: err.Error()})
+			return
+		}
+		response := controlResponse{Thing: thing}
+		if duration > 0 {
+			response.Until = time.Now().Add(duration).Format(time.RFC3339)
+		}
+		writeControlResponse(w, http.StatusOK, response)
+	case http.MethodDelete:
+		if err := s.thingManager.Unacknowledge(thing); err != nil {
+			writeControlResponse(w, http.StatusConflict, controlResponse{Thing: thing, Error: err.Error()})
+			return
+		}
+		writeControlResponse(w, http.StatusOK, controlResponse{Thing: thing})
+	default:
+		writeControlResponse(w, http.StatusMethodNotAllowed, controlResponse{Thing: thing, Error: "method not allowed"})
+	}
+}
+
+func (s *ControlServer) handleDevices(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeControlResponse(w, http.StatusMethodNotAllowed, controlResponse{Error: "method not allowed"})
+		return
+	}
+	writeControlResponse(w, http.StatusOK, controlResponse{Devices: s.devices.Health()})
+}
+
+func writeControlResponse(w http.ResponseWriter, code int, response controlResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.WithError(err).Warn("Could not write control response.")
+	}
+}
+
+// ControlClient talks to the control server of a running auroraops server.
+type ControlClient struct {
+	address    string
+	httpClient *http.Client
+}
+
+func NewControlClient(address string) *ControlClient {
+	return &ControlClient{
+		address: address,
+		httpClient: &http.Client{
+			Timeout: time.Second * 10,
+		},
+	}
+}
+
+// Acknowledge acknowledges a thing's current status for the duration, or until
+// the status changes when the duration is zero. It returns when the
+// acknowledgement expires, which is zero when it does not.
+func (c *ControlClient) Acknowledge(thing string, duration time.Duration) (time.Time, error) {
+	req := acknowledgeRequest{}
+	if duration > 0 {
+		req.For = duration.String()
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return time.Time{}, err
+	}
+	response, err := c.ack(http.MethodPost, thing, bytes.NewReader(body))
+	if err != nil || response.Until == "" {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339, response.Until)
+}
+
+// Unacknowledge clears the acknowledgement of a thing.
+func (c *ControlClient) Unacknowledge(thing string) error {
+	_, err := c.ack(http.MethodDelete, thing, nil)
+	return err
+}
+
+// Devices returns whether each aurora of the server is online.
+func (c *ControlClient) Devices() ([]DeviceHealth, error) {
+	response, err := c.do(http.MethodGet, fmt.Sprintf("http://%s/devices", c.address), nil)
+	return response.Devices, err
+}
+
+func (c *ControlClient) ack(method, thing string, body io.Reader) (controlResponse, error) {
+	return c.do(method, fmt.Sprintf("http://%s/things/%s/ack", c.address, url.PathEscape(thing)), body)
+}
+
+func (c *ControlClient) do(method, u string, body io.Reader) (controlResponse, error) {
+	response := controlResponse{}
+	request, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return response, errors.Wrapf(err, "could not create request: %s %s", method, u)
+	}
+	res, err := c.httpClient.Do(request)
+	if err != nil {
+		return response, errors.Wrap(err, "could not reach the auroraops server")
+	}
+	defer res.Body.Close()
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return response, errors.Wrap(err, "invalid response from the auroraops server")
+	}
+	if response.Error != "" {
+		return response, errors.New(response.Error)
+	}
+	if res.StatusCode != http.StatusOK {
+		return response, fmt.Errorf("error: bad status code from auroraops server: %d", res.StatusCode)
+	}
+	return response, nil
+}
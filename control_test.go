@@ -0,0 +1,212 @@
+package auroraops
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestControlServer serves the control server of a manager whose things
+// blink when they are down, which is critical.
+func newTestControlServer(t *testing.T) (*ThingManager, *ControlClient, func()) {
+	status := map[string]StatusConfigSet{
+		"up": solidStatus("#00ff00"),
+		"down": {Type: "blink", Critical: true, Config: map[string]interface{}{
+			"color":     "#ff0000",
+			"frequency": 50,
+		}},
+	}
+	things := map[string]ThingConfigSet{
+		"website": {Panels: []int{1}},
+		"api":     {Panels: []int{2}},
+	}
+	m, err := newTestThingManager(status, things)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(NewControlServer("", m, nil).server.Handler)
+	return m, NewControlClient(strings.TrimPrefix(ts.URL, "http://")), func() {
+		ts.Close()
+		m.StopAll()
+	}
+}
+
+func TestControlServerAcknowledge(t *testing.T) {
+	m, c, stop := newTestControlServer(t)
+	defer stop()
+
+	if err := m.UpdateThing("website", "down"); err != nil {
+		t.Fatal(err)
+	}
+	until, err := c.Acknowledge("website", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Until(until); d < 59*time.Minute || d > time.Hour {
+		t.Errorf("until = %s, want an hour from now", until)
+	}
+	if m.panelGroups["website"].ack == nil {
+		t.Fatal("website is not acknowledged")
+	}
+	if _, ok := m.panelGroups["website"].action.(*blinkAction); ok {
+		t.Error("acknowledged website still blinks")
+	}
+	if got, want := wall(m)[1], [3]uint8{255, 0, 0}; got != want {
+		t.Errorf("acknowledged website = %v, want %v", got, want)
+	}
+	if m.Critical() {
+		t.Error("acknowledged website is critical")
+	}
+
+	if err := c.Unacknowledge("website"); err != nil {
+		t.Fatal(err)
+	}
+	if m.panelGroups["website"].ack != nil {
+		t.Error("website is still acknowledged")
+	}
+	if _, ok := m.panelGroups["website"].action.(*blinkAction); !ok {
+		t.Error("website does not blink again")
+	}
+	if !m.Critical() {
+		t.Error("unacknowledged website is not critical")
+	}
+}
+
+func TestControlServerAcknowledgeUntilChanged(t *testing.T) {
+	m, c, stop := newTestControlServer(t)
+	defer stop()
+
+	if err := m.UpdateThing("website", "down"); err != nil {
+		t.Fatal(err)
+	}
+	until, err := c.Acknowledge("website", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !until.IsZero() {
+		t.Errorf("until = %s, want none", until)
+	}
+	if err := m.UpdateThing("website", "up"); err != nil {
+		t.Fatal(err)
+	}
+	if m.panelGroups["website"].ack != nil {
+		t.Error("acknowledgement outlived the status")
+	}
+}
+
+func TestControlServerAcknowledgementExpires(t *testing.T) {
+	m, c, stop := newTestControlServer(t)
+	defer stop()
+
+	if err := m.UpdateThing("website", "down"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Acknowledge("website", 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	acknowledged := func() bool {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return m.panelGroups["website"].ack != nil
+	}
+	for deadline := time.Now().Add(time.Second); acknowledged(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("acknowledgement did not expire")
+		}
+	}
+}
+
+func TestControlServerErrors(t *testing.T) {
+	m, c, stop := newTestControlServer(t)
+	defer stop()
+	if err := m.UpdateThing("website", "down"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		body      string
+		wantCode  int
+		wantError string
+	}{
+		{
+			name:      "unknown paths",
+			method:    http.MethodPost,
+			path:      "/things/website",
+			wantCode:  http.StatusNotFound,
+			wantError: "not found",
+		},
+		{
+			name:      "unknown things",
+			method:    http.MethodPost,
+			path:      "/things/database/ack",
+			wantCode:  http.StatusNotFound,
+			wantError: "unknown thing",
+		},
+		{
+			name:      "things without a status",
+			method:    http.MethodPost,
+			path:      "/things/api/ack",
+			wantCode:  http.StatusConflict,
+			wantError: "error: thing has no status to acknowledge",
+		},
+		{
+			name:      "invalid requests",
+			method:    http.MethodPost,
+			path:      "/things/website/ack",
+			body:      "{",
+			wantCode:  http.StatusBadRequest,
+			wantError: "unexpected EOF",
+		},
+		{
+			name:      "invalid durations",
+			method:    http.MethodPost,
+			path:      "/things/website/ack",
+			body:      `{"for": "soon"}`,
+			wantCode:  http.StatusBadRequest,
+			wantError: `time: invalid duration "soon"`,
+		},
+		{
+			name:      "other methods",
+			method:    http.MethodGet,
+			path:      "/things/website/ack",
+			wantCode:  http.StatusMethodNotAllowed,
+			wantError: "method not allowed",
+		},
+		{
+			name:      "changes to devices",
+			method:    http.MethodPost,
+			path:      "/devices",
+			wantCode:  http.StatusMethodNotAllowed,
+			wantError: "method not allowed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := http.NewRequest(tt.method, "http://"+c.address+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := http.DefaultClient.Do(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			response := controlResponse{}
+			if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+			if res.StatusCode != tt.wantCode || response.Error != tt.wantError {
+				t.Errorf("%s %s = %d %q, want %d %q", tt.method, tt.path, res.StatusCode, response.Error, tt.wantCode, tt.wantError)
+			}
+		})
+	}
+	if m.panelGroups["website"].ack != nil {
+		t.Error("website was acknowledged")
+	}
+}
@@ -0,0 +1,115 @@
+package auroraops
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	colorful "github.com/lucasb-eyer/go-colorful"
+	log "github.com/sirupsen/logrus"
+	tomb "gopkg.in/tomb.v2"
+)
+
+type cycleAction struct {
+	panels     []int
+	saturation float64
+	value      float64
+	period     time.Duration
+	phase      float64
+
+	canvas Canvas
+
+	t  tomb.Tomb
+	mu sync.Mutex
+}
+
+// NewCycleAction creates an action that rotates the hue of the panels once per
+// period. The saturation and value of the given color are kept, and each
+// panel is offset from the previous one by phase, a fraction of a full turn.
+func NewCycleAction(canvas Canvas, panels []int, color colorful.Color, period time.Duration, phase float64) (Action, error) {
+	log.Info("New cycle action")
+	if !color.IsValid() {
+		return nil, fmt.Errorf("error: invalid color")
+	}
+	if period <= 0 {
+		return nil, fmt.Errorf("error: period must be greater than zero")
+	}
+	_, saturation, value := color.Hsv()
+	return &cycleAction{
+		panels:     panels,
+		saturation: saturation,
+		value:      value,
+		period:     period,
+		phase:      phase,
+		canvas:     canvas,
+	}, nil
+}
+
+func (a *cycleAction) draw(elapsed time.Duration) {
+	turn := float64(elapsed%a.period) / float64(a.period)
+	for i, panel := range a.panels {
+		hue := math.Mod(turn+a.phase*float64(i), 1)
+		if hue < 0 {
+			hue += 1
+		}
+		a.canvas.SetPanelColor(panel, colorful.Hsv(hue*360, a.saturation, a.value))
+	}
+}
+
+func (a *cycleAction) loop() error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	start := time.Now()
+	a.draw(0)
+	for {
+		select {
+		case t := <-ticker.C:
+			a.draw(t.Sub(start))
+		case <-a.t.Dying():
+			return nil
+		}
+	}
+}
+
+func (a *cycleAction) Start() error {
+	a.t.Go(a.loop)
+	return nil
+}
+
+func (a *cycleAction) Stop(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	log.WithField("action", "cycle").Info("Stopping")
+	a.t.Kill(nil)
+	return a.t.Wait()
+}
+
+// cycleConfig defaults to rotating pure red over five seconds.
+type cycleConfig struct {
+	Color  string        `mapstructure:"color" schema:"color"`
+	Period time.Duration `mapstructure:"period"`
+	Phase  float64       `mapstructure:"phase"`
+}
+
+func init() {
+	RegisterAction("cycle", newCycleFromConfig, cycleConfig{})
+}
+
+func newCycleFromConfig(config map[string]interface{}, ac ActionContext) (Action, error) {
+	cc := cycleConfig{}
+	if err := ac.DecodeConfig(config, &cc); err != nil {
+		return nil, err
+	}
+	color, err := colorOrDefault(cc.Color, colorful.Color{R: 1})
+	if err != nil {
+		return nil, err
+	}
+	period := cc.Period
+	if period <= 0 {
+		period = 5 * time.Second
+	}
+	return NewCycleAction(ac.Canvas, ac.Panels, color, period, cc.Phase)
+}
@@ -0,0 +1,63 @@
+package auroraops
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCycleAction(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		elapsed time.Duration
+		want    map[int]string
+	}{
+		{
+			name:    "starts at red whatever the hue of its color",
+			config:  map[string]interface{}{"color": "#00ff00", "period": "4s"},
+			elapsed: 0,
+			want:    map[int]string{1: "#ff0000", 2: "#ff0000"},
+		},
+		{
+			name:    "rotates the hue over its period",
+			config:  map[string]interface{}{"color": "#ff0000", "period": "3s"},
+			elapsed: time.Second,
+			want:    map[int]string{1: "#00ff00", 2: "#00ff00"},
+		},
+		{
+			name:    "keeps the saturation and value of its color",
+			config:  map[string]interface{}{"color": "#800000", "period": "3s"},
+			elapsed: 2 * time.Second,
+			want:    map[int]string{1: "#000080", 2: "#000080"},
+		},
+		{
+			name:    "offsets each panel by its phase",
+			config:  map[string]interface{}{"period": "3s", "phase": 0.3333333333333333},
+			elapsed: 0,
+			want:    map[int]string{1: "#ff0000", 2: "#00ff00"},
+		},
+		{
+			name:    "starts over every period",
+			config:  map[string]interface{}{"period": "3s", "phase": -0.3333333333333333},
+			elapsed: 3 * time.Second,
+			want:    map[int]string{1: "#ff0000", 2: "#0000ff"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			canvas := recordingCanvas{}
+			action, err := newAction(StatusConfigSet{Type: "cycle", Config: tt.config}, ActionContext{
+				Panels: []int{1, 2},
+				Canvas: canvas,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			action.(*cycleAction).draw(tt.elapsed)
+			if got := canvas.hexes(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("colors = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
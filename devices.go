@@ -0,0 +1,583 @@
+package auroraops
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/ngerakines/auroraops/client"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	tomb "gopkg.in/tomb.v2"
+)
+
+// DeviceConfig describes an aurora, how to reach it and where it is on the
+// wall. The panels of a device are rotated by Rotation degrees counterclockwise
+// about the origin of its own layout and then moved by Offset, an [x, y] in
+// layout units, so that devices hung next to each other share one coordinate
+// space.
+type DeviceConfig struct {
+	Name     string `mapstructure:"name"`
+	URL      string `mapstructure:"url"`
+	Key      string `mapstructure:"key"`
+	Offset   []int  `mapstructure:"offset"`
+	Rotation int    `mapstructure:"rotation"`
+}
+
+// devicePanelSpan separates the panel IDs of devices. The panels of the first
+// device keep their own IDs, and the panels of each other device are offset by
+// devicePanelSpan times its position in the list of devices, so that panel IDs
+// are unique across devices.
+const devicePanelSpan = 1 << 16
+
+// deviceCheckInterval is how often the health of each device is checked.
+const deviceCheckInterval = 30 * time.Second
+
+// ParseDeviceConfig reads the devices section of the configuration. Every
+// device needs a URL and a unique name, except that a single device may be
+// unnamed.
+func ParseDeviceConfig(raw interface{}) ([]DeviceConfig, error) {
+	devices := []DeviceConfig{}
+	if err := decodeConfig(raw, &devices, true); err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for i, device := range devices {
+		if device.URL == "" {
+			return nil, fmt.Errorf("error: device %d has no url", i)
+		}
+		if device.Name == "" && len(devices) > 1 {
+			return nil, fmt.Errorf("error: device %d has no name", i)
+		}
+		if strings.Contains(device.Name, ":") {
+			return nil, fmt.Errorf("error: device name %s cannot contain a colon", device.Name)
+		}
+		if device.Offset != nil && len(device.Offset) != 2 {
+			return nil, fmt.Errorf("error: offset of device %d must be [x, y]", i)
+		}
+		if names[device.Name] {
+			return nil, fmt.Errorf("error: device %s is configured more than once", device.Name)
+		}
+		names[device.Name] = true
+	}
+	return devices, nil
+}
+
+// ParsePanelRef returns the panel ID of a reference to a panel, which is either
+// a panel of the first device such as "13", or a panel of a named device such
+// as "hall:13".
+func ParsePanelRef(devices []DeviceConfig, ref string) (int, error) {
+	name, id := "", ref
+	if i := strings.LastIndex(ref, ":"); i >= 0 {
+		name, id = ref[:i], ref[i+1:]
+	}
+	panel, err := strconv.Atoi(strings.TrimSpace(id))
+	if err != nil || panel < 0 || panel >= devicePanelSpan {
+		return 0, fmt.Errorf("error: invalid panel: %s", ref)
+	}
+	if name == "" {
+		return panel, nil
+	}
+	for i, device := range devices {
+		if device.Name == name {
+			return i*devicePanelSpan + panel, nil
+		}
+	}
+	return 0, fmt.Errorf("error: unknown device: %s", name)
+}
+
+// FormatPanelRef returns the reference to a panel ID that ParsePanelRef reads.
+func FormatPanelRef(devices []DeviceConfig, panel int) string {
+	device, id := panel/devicePanelSpan, panel%devicePanelSpan
+	if device == 0 || device >= len(devices) {
+		return strconv.Itoa(panel)
+	}
+	return fmt.Sprintf("%s:%d", devices[device].Name, id)
+}
+
+// place moves a panel of the device to where it is on the shared wall.
+func (config DeviceConfig) place(panel client.Panel) client.Panel {
+	if config.Rotation%360 != 0 {
+		angle := float64(config.Rotation) * math.Pi / 180
+		x, y := float64(panel.X), float64(panel.Y)
+		panel.X = int(math.Round(x*math.Cos(angle) - y*math.Sin(angle)))
+		panel.Y = int(math.Round(x*math.Sin(angle) + y*math.Cos(angle)))
+		panel.Rotation = ((panel.Rotation+config.Rotation)%360 + 360) % 360
+	}
+	if len(config.Offset) == 2 {
+		panel.X = panel.X + config.Offset[0]
+		panel.Y = panel.Y + config.Offset[1]
+	}
+	return panel
+}
+
+// overlappingDevices returns the pairs of devices, by position, that have
+// panels on top of each other in a layout, such as devices that have no offset.
+func overlappingDevices(layout []*client.Panel) [][2]int {
+	overlapping := [][2]int{}
+	seen := make(map[[2]int]bool)
+	for i, a := range layout {
+		for _, b := range layout[i+1:] {
+			pair := [2]int{a.ID / devicePanelSpan, b.ID / devicePanelSpan}
+			if pair[0] > pair[1] {
+				pair[0], pair[1] = pair[1], pair[0]
+			}
+			if pair[0] == pair[1] || seen[pair] {
+				continue
+			}
+			sideLength := a.SideLength
+			if sideLength == 0 {
+				sideLength = 150
+			}
+			if math.Hypot(float64(a.X-b.X), float64(a.Y-b.Y)) < float64(sideLength)/4 {
+				seen[pair] = true
+				overlapping = append(overlapping, pair)
+			}
+		}
+	}
+	return overlapping
+}
+
+// panelRefHookFunc decodes references to panels of named devices into panel
+// IDs.
+func panelRefHookFunc(devices []DeviceConfig) mapstructure.DecodeHookFuncType {
+	return func(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+		if from.Kind() != reflect.String || to.Kind() != reflect.Int {
+			return data, nil
+		}
+		ref := data.(string)
+		if !strings.Contains(ref, ":") {
+			return data, nil
+		}
+		return ParsePanelRef(devices, ref)
+	}
+}
+
+// DeviceSet drives several auroras as one. Panels of every device are given
+// unique IDs, frames are split between the devices, and each device is checked
+// and reconnected on its own so that an offline device does not hold up the
+// others. A device set with a single device behaves like its client.
+type DeviceSet struct {
+	devices []*device
+
+	// Reconnected is called after a device comes back online and has been given
+	// back to auroraops, so that everything can be drawn again.
+	Reconnected func()
+
+	// scale is the fraction of its own brightness each device is set to.
+	scale    float64
+	watching bool
+	t        tomb.Tomb
+	mu       sync.Mutex
+}
+
+type device struct {
+	config  DeviceConfig
+	offset  int
+	reader  client.AuroraClient
+	writer  client.AuroraClient
+	panels  []*client.Panel
+	online  bool
+	started bool
+	// brightness is the brightness of the device when it was first taken over.
+	brightness int
+
+	mu sync.Mutex
+}
+
+// NewDeviceSet creates a device set that can read from its devices. Start
+// takes over their panels.
+func NewDeviceSet(configs []DeviceConfig) (*DeviceSet, error) {
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("error: no devices are configured")
+	}
+	s := &DeviceSet{scale: 1}
+	for i, config := range configs {
+		reader, err := client.NewReadOnly(config.URL, config.Key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "device %s", config.Name)
+		}
+		s.devices = append(s.devices, &device{
+			config: config,
+			offset: i * devicePanelSpan,
+			reader: reader,
+			online: true,
+		})
+	}
+	return s, nil
+}
+
+// Start takes over the panels of every device that can be reached, and keeps
+// checking every device, reconnecting those that come back online. It fails
+// only when no device can be reached.
+func (s *DeviceSet) Start() error {
+	var wg sync.WaitGroup
+	for _, d := range s.devices {
+		d.started = true
+		wg.Add(1)
+		go func(d *device) {
+			defer wg.Done()
+			if err := d.connect(); err != nil {
+				d.setOffline(err)
+			}
+		}(d)
+	}
+	wg.Wait()
+
+	online := 0
+	for _, d := range s.devices {
+		if d.isOnline() {
+			online = online + 1
+		}
+	}
+	if online == 0 {
+		return fmt.Errorf("error: no device could be reached")
+	}
+	s.watching = true
+	for _, d := range s.devices {
+		d := d
+		s.t.Go(func() error {
+			return s.watch(d)
+		})
+	}
+	return nil
+}
+
+func (d *device) connect() error {
+	writer, err := client.NewWithToken(d.config.URL, d.config.Key)
+	if err != nil {
+		return err
+	}
+	info, err := d.reader.GetInfo()
+	if err != nil {
+		writer.Stop()
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.writer != nil {
+		d.writer.Stop()
+	}
+	d.writer = writer
+	d.panels = info.Panels
+	d.online = true
+	if d.brightness <= 0 {
+		d.brightness = info.State.Brightness.Value
+		if d.brightness <= 0 {
+			d.brightness = 100
+		}
+	}
+	return nil
+}
+
+func (d *device) setOffline(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.online {
+		log.WithError(err).WithField("device", d.config.Name).Warn("Device is offline.")
+	}
+	d.online = false
+}
+
+func (d *device) isOnline() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.online
+}
+
+// watch checks a device, reconnecting it when it comes back online or has
+// left external control, such as after losing power.
+func (s *DeviceSet) watch(d *device) error {
+	ticker := time.NewTicker(deviceCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			info, err := d.reader.GetInfo()
+			if err != nil {
+				d.setOffline(err)
+				continue
+			}
+			if d.isOnline() && info.Effects.Select == "*ExtControl*" {
+				continue
+			}
+			if err := d.connect(); err != nil {
+				d.setOffline(err)
+				continue
+			}
+			log.WithField("device", d.config.Name).Info("Device is online.")
+			s.mu.Lock()
+			scale := s.scale
+			s.mu.Unlock()
+			d.mu.Lock()
+			writer := d.writer
+			d.mu.Unlock()
+			if err := writer.SetBrightness(d.scaledBrightness(scale)); err != nil {
+				log.WithError(err).WithField("device", d.config.Name).Warn("Could not restore brightness.")
+			}
+			if s.Reconnected != nil {
+				s.Reconnected()
+			}
+		case <-s.t.Dying():
+			return nil
+		}
+	}
+}
+
+// DeviceHealth is whether a device of a DeviceSet is online.
+type DeviceHealth struct {
+	Name   string `json:"name,omitempty"`
+	URL    string `json:"url"`
+	Online bool   `json:"online"`
+}
+
+// Health returns whether each device is online, in the order of the devices.
+func (s *DeviceSet) Health() []DeviceHealth {
+	health := make([]DeviceHealth, len(s.devices))
+	for i, d := range s.devices {
+		health[i] = DeviceHealth{Name: d.config.Name, URL: d.config.URL, Online: d.isOnline()}
+	}
+	return health
+}
+
+// Authorize is not supported by a device set; devices are paired one at a time.
+func (s *DeviceSet) Authorize() (string, error) {
+	return "", fmt.Errorf("error: devices must be authorized one at a time")
+}
+
+// GetInfo returns the information of the first device that is online, with the
+// panels of every device. Offline devices are not contacted, and their panels
+// are the ones last seen.
+func (s *DeviceSet) GetInfo() (*client.HardwareInfo, error) {
+	infos := make([]*client.HardwareInfo, len(s.devices))
+	errs := make([]error, len(s.devices))
+	var wg sync.WaitGroup
+	for i, d := range s.devices {
+		if !d.isOnline() {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, d *device) {
+			defer wg.Done()
+			infos[i], errs[i] = d.reader.GetInfo()
+		}(i, d)
+	}
+	wg.Wait()
+
+	var merged *client.HardwareInfo
+	for i, d := range s.devices {
+		if errs[i] != nil {
+			if len(s.devices) == 1 {
+				return nil, errs[i]
+			}
+			if d.started {
+				d.setOffline(errs[i])
+			} else {
+				log.WithError(errs[i]).WithField("device", d.config.Name).Warn("Could not read device.")
+			}
+		}
+		d.mu.Lock()
+		if infos[i] != nil {
+			d.panels = infos[i].Panels
+		}
+		panels := d.panels
+		d.mu.Unlock()
+
+		if merged == nil && infos[i] != nil {
+			info := *infos[i]
+			merged = &info
+			merged.Panels = []*client.Panel{}
+		}
+		for _, panel := range panels {
+			p := d.config.place(*panel)
+			p.ID = p.ID + d.offset
+			if merged == nil {
+				merged = &client.HardwareInfo{}
+			}
+			merged.Panels = append(merged.Panels, &p)
+		}
+	}
+	if merged == nil {
+		return nil, fmt.Errorf("error: no device could be reached")
+	}
+	return merged, nil
+}
+
+// SetPanelColor sets the color of a panel of the first device.
+func (s *DeviceSet) SetPanelColor(panel, r, g, b byte) error {
+	return s.SetPanelColors([]*client.PanelColorCommand{{ID: int(panel), R: r, G: g, B: b}})
+}
+
+// SetPanelColors splits a frame between the devices, skipping devices that are
+// offline.
+func (s *DeviceSet) SetPanelColors(commands []*client.PanelColorCommand) error {
+	frames := make([][]*client.PanelColorCommand, len(s.devices))
+	for _, command := range commands {
+		i := command.ID / devicePanelSpan
+		if i < 0 || i >= len(s.devices) {
+			continue
+		}
+		frames[i] = append(frames[i], &client.PanelColorCommand{
+			ID: command.ID % devicePanelSpan,
+			R:  command.R,
+			G:  command.G,
+			B:  command.B,
+		})
+	}
+	var errs []string
+	for i, d := range s.devices {
+		if len(frames[i]) == 0 {
+			continue
+		}
+		d.mu.Lock()
+		writer, online := d.writer, d.online
+		d.mu.Unlock()
+		if writer == nil || !online {
+			continue
+		}
+		if err := writer.SetPanelColors(frames[i]); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", d.config.Name, err))
+		}
+	}
+	return joinErrors(errs)
+}
+
+// SetPower turns every device that is online on or off.
+func (s *DeviceSet) SetPower(on bool) error {
+	return s.each(func(d *device, writer client.AuroraClient) error {
+		return writer.SetPower(on)
+	})
+}
+
+// SetBrightness sets the brightness of every device that is online.
+func (s *DeviceSet) SetBrightness(brightness int) error {
+	return s.each(func(d *device, writer client.AuroraClient) error {
+		return writer.SetBrightness(brightness)
+	})
+}
+
+// ScaleBrightness sets every device to a fraction of its own brightness, as it
+// was when the device was first taken over. Devices that come back online are
+// set to the same fraction.
+func (s *DeviceSet) ScaleBrightness(scale float64) error {
+	s.mu.Lock()
+	s.scale = scale
+	s.mu.Unlock()
+	return s.each(func(d *device, writer client.AuroraClient) error {
+		return writer.SetBrightness(d.scaledBrightness(scale))
+	})
+}
+
+func (d *device) scaledBrightness(scale float64) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return int(math.Round(float64(d.brightness) * scale))
+}
+
+// each calls a function for every device that is online at once, so that a
+// slow device does not hold up the others.
+func (s *DeviceSet) each(f func(*device, client.AuroraClient) error) error {
+	errs := make([]string, len(s.devices))
+	var wg sync.WaitGroup
+	for i, d := range s.devices {
+		d.mu.Lock()
+		writer, online := d.writer, d.online
+		d.mu.Unlock()
+		if writer == nil || !online {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, d *device) {
+			defer wg.Done()
+			if err := f(d, writer); err != nil {
+				errs[i] = fmt.Sprintf("%s: %s", d.config.Name, err)
+			}
+		}(i, d)
+	}
+	wg.Wait()
+	return joinErrors(errs)
+}
+
+// TouchEvents streams the touches of every device that reports them.
+func (s *DeviceSet) TouchEvents(stop <-chan struct{}) (<-chan int, error) {
+	touches := make(chan int)
+	var wg sync.WaitGroup
+	supported := false
+	for _, d := range s.devices {
+		d.mu.Lock()
+		writer := d.writer
+		d.mu.Unlock()
+		if writer == nil {
+			writer = d.reader
+		}
+		events, err := writer.TouchEvents(stop)
+		if err == client.ErrTouchUnsupported {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "device %s", d.config.Name)
+		}
+		supported = true
+		wg.Add(1)
+		go func(d *device) {
+			defer wg.Done()
+			for panel := range events {
+				select {
+				case touches <- panel + d.offset:
+				case <-stop:
+					return
+				}
+			}
+		}(d)
+	}
+	if !supported {
+		return nil, client.ErrTouchUnsupported
+	}
+	go func() {
+		wg.Wait()
+		close(touches)
+	}()
+	return touches, nil
+}
+
+// Stop stops checking the devices and gives back their panels.
+func (s *DeviceSet) Stop() error {
+	if s.watching {
+		s.t.Kill(nil)
+		s.t.Wait()
+	}
+
+	var errs []string
+	for _, d := range s.devices {
+		d.mu.Lock()
+		writer := d.writer
+		d.writer = nil
+		d.mu.Unlock()
+		if writer == nil {
+			continue
+		}
+		if err := writer.Stop(); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", d.config.Name, err))
+		}
+	}
+	return joinErrors(errs)
+}
+
+func joinErrors(errs []string) error {
+	messages := []string{}
+	for _, err := range errs {
+		if err != "" {
+			messages = append(messages, err)
+		}
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(messages, "; "))
+}
@@ -0,0 +1,230 @@
+package auroraops
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ngerakines/auroraops/client"
+)
+
+func TestParsePanelRef(t *testing.T) {
+	devices := []DeviceConfig{{Name: "hall"}, {Name: "lobby"}, {Name: "back:room"}}
+	tests := []struct {
+		ref     string
+		want    int
+		wantErr bool
+	}{
+		{ref: "13", want: 13},
+		{ref: "0", want: 0},
+		{ref: "hall:13", want: 13},
+		{ref: "lobby:13", want: devicePanelSpan + 13},
+		{ref: "lobby: 13", want: devicePanelSpan + 13},
+		{ref: "attic:13", wantErr: true},
+		{ref: "Lobby:13", wantErr: true},
+		{ref: "lobby:", wantErr: true},
+		{ref: "lobby:-1", wantErr: true},
+		{ref: "65536", wantErr: true},
+		{ref: "thirteen", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := ParsePanelRef(devices, tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePanelRef(%q) error = %v, want error %v", tt.ref, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParsePanelRef(%q) = %d, want %d", tt.ref, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatPanelRef(t *testing.T) {
+	devices := []DeviceConfig{{Name: "hall"}, {Name: "lobby"}}
+	tests := []struct {
+		name    string
+		devices []DeviceConfig
+		panel   int
+		want    string
+	}{
+		{name: "first device", devices: devices, panel: 13, want: "13"},
+		{name: "other device", devices: devices, panel: devicePanelSpan + 13, want: "lobby:13"},
+		{name: "unknown device", devices: devices, panel: 2*devicePanelSpan + 13, want: "131085"},
+		{name: "no devices", panel: 13, want: "13"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FormatPanelRef(tt.devices, tt.panel)
+			if got != tt.want {
+				t.Errorf("FormatPanelRef(%d) = %s, want %s", tt.panel, got, tt.want)
+			}
+			if tt.panel < len(tt.devices)*devicePanelSpan {
+				if back, err := ParsePanelRef(tt.devices, got); err != nil || back != tt.panel {
+					t.Errorf("ParsePanelRef(%s) = %d, %v, want %d", got, back, err, tt.panel)
+				}
+			}
+		})
+	}
+}
+
+func TestParseDeviceConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     interface{}
+		wantErr bool
+	}{
+		{
+			name: "one unnamed device",
+			raw:  []interface{}{map[string]interface{}{"url": "http://hall"}},
+		},
+		{
+			name: "named devices",
+			raw: []interface{}{
+				map[string]interface{}{"name": "hall", "url": "http://hall"},
+				map[string]interface{}{"name": "lobby", "url": "http://lobby", "offset": []interface{}{100, 0}},
+			},
+		},
+		{
+			name:    "a device without a url",
+			raw:     []interface{}{map[string]interface{}{"name": "hall"}},
+			wantErr: true,
+		},
+		{
+			name: "several devices without names",
+			raw: []interface{}{
+				map[string]interface{}{"url": "http://hall"},
+				map[string]interface{}{"url": "http://lobby"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "a name with a colon",
+			raw: []interface{}{
+				map[string]interface{}{"name": "back:room", "url": "http://hall"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "the same name twice",
+			raw: []interface{}{
+				map[string]interface{}{"name": "hall", "url": "http://hall"},
+				map[string]interface{}{"name": "hall", "url": "http://lobby"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "an offset that is not a point",
+			raw: []interface{}{
+				map[string]interface{}{"name": "hall", "url": "http://hall", "offset": []interface{}{100}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "an unknown setting",
+			raw: []interface{}{
+				map[string]interface{}{"name": "hall", "url": "http://hall", "color": "#ffffff"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseDeviceConfig(tt.raw); (err != nil) != tt.wantErr {
+				t.Errorf("ParseDeviceConfig() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPlace(t *testing.T) {
+	tests := []struct {
+		name   string
+		config DeviceConfig
+		panel  client.Panel
+		want   client.Panel
+	}{
+		{
+			name:  "keeps panels in place by default",
+			panel: client.Panel{ID: 1, X: 100, Y: 200, Rotation: 60},
+			want:  client.Panel{ID: 1, X: 100, Y: 200, Rotation: 60},
+		},
+		{
+			name:   "moves panels by the offset",
+			config: DeviceConfig{Offset: []int{1200, 50}},
+			panel:  client.Panel{ID: 1, X: 100, Y: 200, Rotation: 60},
+			want:   client.Panel{ID: 1, X: 1300, Y: 250, Rotation: 60},
+		},
+		{
+			name:   "turns panels counterclockwise about the origin",
+			config: DeviceConfig{Rotation: 90},
+			panel:  client.Panel{ID: 1, X: 100, Y: 0, Rotation: 60},
+			want:   client.Panel{ID: 1, X: 0, Y: 100, Rotation: 150},
+		},
+		{
+			name:   "turns panels before moving them",
+			config: DeviceConfig{Offset: []int{1200, 0}, Rotation: 90},
+			panel:  client.Panel{ID: 1, X: 100, Y: 0},
+			want:   client.Panel{ID: 1, X: 1200, Y: 100, Rotation: 90},
+		},
+		{
+			name:   "turns panels clockwise by a negative rotation",
+			config: DeviceConfig{Rotation: -90},
+			panel:  client.Panel{ID: 1, X: 100, Y: 0},
+			want:   client.Panel{ID: 1, X: 0, Y: -100, Rotation: 270},
+		},
+		{
+			name:   "wraps the rotation of panels",
+			config: DeviceConfig{Rotation: 180},
+			panel:  client.Panel{ID: 1, X: 100, Y: 50, Rotation: 300},
+			want:   client.Panel{ID: 1, X: -100, Y: -50, Rotation: 120},
+		},
+		{
+			name:   "ignores whole turns",
+			config: DeviceConfig{Rotation: 360},
+			panel:  client.Panel{ID: 1, X: 100, Y: 50, Rotation: 300},
+			want:   client.Panel{ID: 1, X: 100, Y: 50, Rotation: 300},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.place(tt.panel); got != tt.want {
+				t.Errorf("place() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOverlappingDevices(t *testing.T) {
+	tests := []struct {
+		name   string
+		layout []*client.Panel
+		want   [][2]int
+	}{
+		{
+			name:   "devices apart",
+			layout: []*client.Panel{{ID: 1}, {ID: 2, X: 150}, {ID: devicePanelSpan + 1, X: 1200}},
+			want:   [][2]int{},
+		},
+		{
+			name:   "panels of one device close together",
+			layout: []*client.Panel{{ID: 1}, {ID: 2, X: 10}},
+			want:   [][2]int{},
+		},
+		{
+			name: "devices on top of each other",
+			layout: []*client.Panel{
+				{ID: 1}, {ID: 2, X: 150},
+				{ID: devicePanelSpan + 1, X: 150}, {ID: devicePanelSpan + 2, X: 5},
+				{ID: 2*devicePanelSpan + 1, X: 1200},
+			},
+			want: [][2]int{{0, 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := overlappingDevices(tt.layout); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("overlappingDevices() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
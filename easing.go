@@ -0,0 +1,78 @@
+package auroraops
+
+import (
+	"fmt"
+	"math"
+
+	colorful "github.com/lucasb-eyer/go-colorful"
+)
+
+// Easing maps linear progress between two keypoints, from 0 to 1, onto a
+// curve.
+type Easing func(t float64) float64
+
+// Blend mixes two colors in a color space. A t of 0 is c1 and 1 is c2.
+type Blend func(c1, c2 colorful.Color, t float64) colorful.Color
+
+var easings = map[string]Easing{
+	"linear": func(t float64) float64 {
+		return t
+	},
+	"ease-in": func(t float64) float64 {
+		return t * t
+	},
+	"ease-out": func(t float64) float64 {
+		return t * (2 - t)
+	},
+	"ease-in-out": func(t float64) float64 {
+		if t < 0.5 {
+			return 2 * t * t
+		}
+		return -1 + (4-2*t)*t
+	},
+	"sine": func(t float64) float64 {
+		return (1 - math.Cos(t*math.Pi)) / 2
+	},
+	"step": func(t float64) float64 {
+		if t < 1 {
+			return 0
+		}
+		return 1
+	},
+}
+
+var blends = map[string]Blend{
+	"hcl": func(c1, c2 colorful.Color, t float64) colorful.Color {
+		return c1.BlendHcl(c2, t).Clamped()
+	},
+	"rgb": func(c1, c2 colorful.Color, t float64) colorful.Color {
+		return c1.BlendRgb(c2, t).Clamped()
+	},
+	"lab": func(c1, c2 colorful.Color, t float64) colorful.Color {
+		return c1.BlendLab(c2, t).Clamped()
+	},
+}
+
+// EasingFor returns the named easing curve. An empty name is linear.
+func EasingFor(name string) (Easing, error) {
+	if name == "" {
+		name = "linear"
+	}
+	easing, ok := easings[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported easing: %s", name)
+	}
+	return easing, nil
+}
+
+// BlendFor returns the named blend color space. An empty name is HCL.
+func BlendFor(name string) (Blend, error) {
+	if name == "" {
+		name = "hcl"
+	}
+	blend, ok := blends[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported blend: %s", name)
+	}
+	return blend, nil
+}
@@ -0,0 +1,71 @@
+package auroraops
+
+import (
+	"math"
+	"testing"
+
+	colorful "github.com/lucasb-eyer/go-colorful"
+)
+
+func TestEasings(t *testing.T) {
+	tests := []struct {
+		name string
+		want [4]float64
+	}{
+		{name: "", want: [4]float64{0, 0.25, 0.5, 1}},
+		{name: "linear", want: [4]float64{0, 0.25, 0.5, 1}},
+		{name: "ease-in", want: [4]float64{0, 0.0625, 0.25, 1}},
+		{name: "ease-out", want: [4]float64{0, 0.4375, 0.75, 1}},
+		{name: "ease-in-out", want: [4]float64{0, 0.125, 0.5, 1}},
+		{name: "sine", want: [4]float64{0, (1 - math.Sqrt2/2) / 2, 0.5, 1}},
+		{name: "step", want: [4]float64{0, 0, 0, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			easing, err := EasingFor(tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, at := range []float64{0, 0.25, 0.5, 1} {
+				if got := easing(at); math.Abs(got-tt.want[i]) > 1e-9 {
+					t.Errorf("%s(%v) = %v, want %v", tt.name, at, got, tt.want[i])
+				}
+			}
+		})
+	}
+	if _, err := EasingFor("bounce"); err == nil {
+		t.Error("EasingFor(bounce) did not fail")
+	}
+}
+
+func TestBlends(t *testing.T) {
+	tests := []struct {
+		name string
+		want colorful.Color
+	}{
+		{name: "rgb", want: colorful.Color{R: 0.5, B: 0.5}},
+		{name: "lab", want: red.BlendLab(blue, 0.5).Clamped()},
+		{name: "hcl", want: red.BlendHcl(blue, 0.5).Clamped()},
+		{name: "", want: red.BlendHcl(blue, 0.5).Clamped()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blend, err := BlendFor(tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := blend(red, blue, 0); !got.AlmostEqualRgb(red) {
+				t.Errorf("blend at 0 = %v, want %v", got, red)
+			}
+			if got := blend(red, blue, 0.5); !got.AlmostEqualRgb(tt.want) {
+				t.Errorf("blend at 0.5 = %v, want %v", got, tt.want)
+			}
+			if got := blend(red, blue, 1); !got.AlmostEqualRgb(blue) {
+				t.Errorf("blend at 1 = %v, want %v", got, blue)
+			}
+		})
+	}
+	if _, err := BlendFor("hsv"); err == nil {
+		t.Error("BlendFor(hsv) did not fail")
+	}
+}
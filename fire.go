@@ -0,0 +1,148 @@
+package auroraops
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	tomb "gopkg.in/tomb.v2"
+)
+
+// firePalette is used by fire actions that are not given their own stops.
+var firePalette = []ColorStop{
+	{Value: 0, Color: "#000000"},
+	{Value: 0.4, Color: "#8B0000"},
+	{Value: 0.7, Color: "#FF4500"},
+	{Value: 0.9, Color: "#FFA500"},
+	{Value: 1, Color: "#FFD700"},
+}
+
+type fireAction struct {
+	panels  []int
+	palette *ValueGradient
+	period  time.Duration
+	heat    []float64
+	target  []float64
+	rng     *rand.Rand
+
+	canvas Canvas
+
+	t  tomb.Tomb
+	mu sync.Mutex
+}
+
+// NewFireAction creates an action that flickers panels through a palette that
+// maps heat, from 0 to 1, to colors. Each panel drifts towards a random heat
+// that changes about once per period. The same seed always produces the same
+// flames.
+func NewFireAction(canvas Canvas, panels []int, palette *ValueGradient, period time.Duration, seed int64) (Action, error) {
+	log.Info("New fire action")
+	if period <= 0 {
+		return nil, fmt.Errorf("error: period must be greater than zero")
+	}
+	rng := rand.New(rand.NewSource(seed))
+	heat := make([]float64, len(panels))
+	target := make([]float64, len(panels))
+	for i := range panels {
+		heat[i] = 0.5 + 0.5*rng.Float64()
+		target[i] = heat[i]
+	}
+	return &fireAction{
+		panels:  panels,
+		palette: palette,
+		period:  period,
+		heat:    heat,
+		target:  target,
+		rng:     rng,
+		canvas:  canvas,
+	}, nil
+}
+
+// step advances every panel by one tick of the given length and draws them.
+func (a *fireAction) step(tick time.Duration) {
+	chance := float64(tick) / float64(a.period)
+	for i, panel := range a.panels {
+		if a.rng.Float64() < chance {
+			a.target[i] = 0.3 + 0.7*a.rng.Float64()
+		}
+		a.heat[i] += (a.target[i] - a.heat[i]) * 0.35
+		a.canvas.SetPanelColor(panel, a.palette.ColorFor(a.heat[i]))
+	}
+}
+
+func (a *fireAction) loop() error {
+	tick := 50 * time.Millisecond
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			a.step(tick)
+		case <-a.t.Dying():
+			return nil
+		}
+	}
+}
+
+func (a *fireAction) Start() error {
+	a.t.Go(a.loop)
+	return nil
+}
+
+func (a *fireAction) Stop(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	log.WithField("action", "fire").Info("Stopping")
+	a.t.Kill(nil)
+	return a.t.Wait()
+}
+
+// fireConfig defaults to the warm palette, changing about every 200ms. An
+// unset Seed is picked at random.
+type fireConfig struct {
+	Stops  []ColorStop   `mapstructure:"stops"`
+	Period time.Duration `mapstructure:"period"`
+	Easing string        `mapstructure:"easing"`
+	Blend  string        `mapstructure:"blend"`
+	Seed   int64         `mapstructure:"seed"`
+}
+
+func init() {
+	RegisterAction("fire", newFireFromConfig, fireConfig{})
+}
+
+func newFireFromConfig(config map[string]interface{}, ac ActionContext) (Action, error) {
+	fc := fireConfig{}
+	if err := ac.DecodeConfig(config, &fc); err != nil {
+		return nil, err
+	}
+	stops := fc.Stops
+	if len(stops) == 0 {
+		stops = firePalette
+	}
+	easing, err := EasingFor(fc.Easing)
+	if err != nil {
+		return nil, err
+	}
+	blend, err := BlendFor(fc.Blend)
+	if err != nil {
+		return nil, err
+	}
+	palette, err := NewValueGradient(stops, easing, blend)
+	if err != nil {
+		return nil, err
+	}
+	period := fc.Period
+	if period <= 0 {
+		period = 200 * time.Millisecond
+	}
+	seed := fc.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return NewFireAction(ac.Canvas, ac.Panels, palette, period, seed)
+}
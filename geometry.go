@@ -0,0 +1,99 @@
+package auroraops
+
+import (
+	"math"
+	"sort"
+
+	"github.com/ngerakines/auroraops/client"
+)
+
+// centroid returns the average position of the panels.
+func centroid(panels []*client.Panel) (float64, float64) {
+	if len(panels) == 0 {
+		return 0, 0
+	}
+	var x, y float64
+	for _, panel := range panels {
+		x += float64(panel.X)
+		y += float64(panel.Y)
+	}
+	return x / float64(len(panels)), y / float64(len(panels))
+}
+
+// normalize scales values so that the smallest is 0 and the largest is 1.
+func normalize(values []float64) []float64 {
+	if len(values) == 0 {
+		return values
+	}
+	min, max := values[0], values[0]
+	for _, v := range values {
+		min = math.Min(min, v)
+		max = math.Max(max, v)
+	}
+	normalized := make([]float64, len(values))
+	if max == min {
+		return normalized
+	}
+	for i, v := range values {
+		normalized[i] = (v - min) / (max - min)
+	}
+	return normalized
+}
+
+// projections returns how far along a direction, in degrees counter-clockwise
+// from the positive x axis, each panel is. Values are normalized.
+func projections(panels []*client.Panel, direction float64) []float64 {
+	radians := direction * math.Pi / 180
+	dx, dy := math.Cos(radians), math.Sin(radians)
+	values := make([]float64, len(panels))
+	for i, panel := range panels {
+		values[i] = float64(panel.X)*dx + float64(panel.Y)*dy
+	}
+	return normalize(values)
+}
+
+// inDirection returns the IDs of the panels sorted by how far along a
+// direction they are.
+func inDirection(panels []*client.Panel, direction float64) []int {
+	fields := projections(panels, direction)
+	order := make([]int, len(panels))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return fields[order[i]] < fields[order[j]]
+	})
+	ids := make([]int, len(panels))
+	for i, index := range order {
+		ids[i] = panels[index].ID
+	}
+	return ids
+}
+
+// distances returns how far each panel is from a point. Values are
+// normalized.
+func distances(panels []*client.Panel, x, y float64) []float64 {
+	values := make([]float64, len(panels))
+	for i, panel := range panels {
+		values[i] = math.Hypot(float64(panel.X)-x, float64(panel.Y)-y)
+	}
+	return normalize(values)
+}
+
+// angles returns the angle of each panel around a point as a fraction of a
+// full turn, from 0 up to but not including 1.
+func angles(panels []*client.Panel, x, y float64) []float64 {
+	values := make([]float64, len(panels))
+	for i, panel := range panels {
+		a := math.Atan2(float64(panel.Y)-y, float64(panel.X)-x) / (2 * math.Pi)
+		if a < 0 {
+			a += 1
+		}
+		values[i] = a
+	}
+	return values
+}
+
+func clamp(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
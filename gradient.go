@@ -0,0 +1,156 @@
+package auroraops
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	colorful "github.com/lucasb-eyer/go-colorful"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// ColorStop is a color at a numeric status value.
+type ColorStop struct {
+	Value float64 `mapstructure:"value"`
+	Color string  `mapstructure:"color" schema:"color"`
+}
+
+// ValueGradient maps numeric values onto colors through a list of stops.
+// Values outside of the stops take the color of the nearest one.
+type ValueGradient struct {
+	table  gradientTable
+	min    float64
+	max    float64
+	easing Easing
+	blend  Blend
+}
+
+func NewValueGradient(stops []ColorStop, easing Easing, blend Blend) (*ValueGradient, error) {
+	if len(stops) == 0 {
+		return nil, fmt.Errorf("error: a gradient needs at least one stop")
+	}
+	sorted := make([]ColorStop, len(stops))
+	copy(sorted, stops)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Value < sorted[j].Value
+	})
+	vg := &ValueGradient{
+		min:    sorted[0].Value,
+		max:    sorted[len(sorted)-1].Value,
+		easing: easing,
+		blend:  blend,
+	}
+	for _, stop := range sorted {
+		color, err := colorful.Hex(stop.Color)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid color: %s", stop.Color)
+		}
+		pos := 0.0
+		if vg.max > vg.min {
+			pos = (stop.Value - vg.min) / (vg.max - vg.min)
+		}
+		vg.table = append(vg.table, struct {
+			Col colorful.Color
+			Pos float64
+		}{color, pos})
+	}
+	return vg, nil
+}
+
+func (vg *ValueGradient) ColorFor(value float64) colorful.Color {
+	if len(vg.table) == 1 || value <= vg.min {
+		return vg.table[0].Col
+	}
+	if value >= vg.max {
+		return vg.table[len(vg.table)-1].Col
+	}
+	return vg.table.interpolate((value-vg.min)/(vg.max-vg.min), vg.easing, vg.blend)
+}
+
+type gradientAction struct {
+	panels   []int
+	gradient *ValueGradient
+	value    float64
+
+	canvas Canvas
+
+	mu sync.Mutex
+}
+
+// NewGradientAction creates an action that fills panels with the color the
+// gradient maps the value to.
+func NewGradientAction(canvas Canvas, panels []int, gradient *ValueGradient, value float64) (Action, error) {
+	log.Info("New gradient action")
+	return &gradientAction{
+		panels:   panels,
+		gradient: gradient,
+		value:    value,
+		canvas:   canvas,
+	}, nil
+}
+
+func (a *gradientAction) draw() {
+	color := a.gradient.ColorFor(a.value)
+	log.WithFields(log.Fields{
+		"action": "gradient",
+		"value":  a.value,
+		"color":  color.Hex(),
+	}).Debug("Drawing")
+	for _, panel := range a.panels {
+		a.canvas.SetPanelColor(panel, color)
+	}
+}
+
+func (a *gradientAction) Start() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.draw()
+	return nil
+}
+
+func (a *gradientAction) SetValue(value float64) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.value = value
+	a.draw()
+	return nil
+}
+
+func (a *gradientAction) Stop(ctx context.Context) error {
+	log.WithField("action", "gradient").Info("Stopping")
+	return nil
+}
+
+type gradientConfig struct {
+	Stops  []ColorStop `mapstructure:"stops"`
+	Easing string      `mapstructure:"easing"`
+	Blend  string      `mapstructure:"blend"`
+}
+
+func init() {
+	RegisterAction("gradient", newGradientFromConfig, gradientConfig{})
+}
+
+func newGradientFromConfig(config map[string]interface{}, ac ActionContext) (Action, error) {
+	gc := gradientConfig{}
+	if err := ac.DecodeConfig(config, &gc); err != nil {
+		return nil, err
+	}
+	easing, err := EasingFor(gc.Easing)
+	if err != nil {
+		return nil, err
+	}
+	blend, err := BlendFor(gc.Blend)
+	if err != nil {
+		return nil, err
+	}
+	gradient, err := NewValueGradient(gc.Stops, easing, blend)
+	if err != nil {
+		return nil, err
+	}
+	return NewGradientAction(ac.Canvas, ac.Panels, gradient, ac.Value)
+}
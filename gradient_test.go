@@ -0,0 +1,96 @@
+package auroraops
+
+import (
+	"testing"
+
+	colorful "github.com/lucasb-eyer/go-colorful"
+)
+
+func TestValueGradient(t *testing.T) {
+	rgb := blends["rgb"]
+	tests := []struct {
+		name   string
+		stops  []ColorStop
+		easing Easing
+		value  float64
+		want   colorful.Color
+	}{
+		{
+			name:   "takes the first stop below the range",
+			stops:  []ColorStop{{Value: 0, Color: "#ff0000"}, {Value: 100, Color: "#00ff00"}},
+			easing: easings["linear"],
+			value:  -5,
+			want:   colorful.Color{R: 1},
+		},
+		{
+			name:   "takes the last stop above the range",
+			stops:  []ColorStop{{Value: 0, Color: "#ff0000"}, {Value: 100, Color: "#00ff00"}},
+			easing: easings["linear"],
+			value:  200,
+			want:   colorful.Color{G: 1},
+		},
+		{
+			name:   "blends between stops",
+			stops:  []ColorStop{{Value: 0, Color: "#ff0000"}, {Value: 100, Color: "#00ff00"}},
+			easing: easings["linear"],
+			value:  50,
+			want:   colorful.Color{R: 0.5, G: 0.5},
+		},
+		{
+			name:   "sorts the stops",
+			stops:  []ColorStop{{Value: 100, Color: "#00ff00"}, {Value: 0, Color: "#ff0000"}},
+			easing: easings["linear"],
+			value:  25,
+			want:   colorful.Color{R: 0.75, G: 0.25},
+		},
+		{
+			name:   "eases between stops",
+			stops:  []ColorStop{{Value: 0, Color: "#ff0000"}, {Value: 100, Color: "#00ff00"}},
+			easing: easings["ease-in"],
+			value:  50,
+			want:   colorful.Color{R: 0.75, G: 0.25},
+		},
+		{
+			name:   "blends only the stops around the value",
+			stops:  []ColorStop{{Value: 0, Color: "#000000"}, {Value: 10, Color: "#ffffff"}, {Value: 20, Color: "#0000ff"}},
+			easing: easings["linear"],
+			value:  15,
+			want:   colorful.Color{R: 0.5, G: 0.5, B: 1},
+		},
+		{
+			name:   "gives one stop everywhere",
+			stops:  []ColorStop{{Value: 10, Color: "#0000ff"}},
+			easing: easings["linear"],
+			value:  50,
+			want:   colorful.Color{B: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vg, err := NewValueGradient(tt.stops, tt.easing, rgb)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := vg.ColorFor(tt.value); !got.AlmostEqualRgb(tt.want) {
+				t.Errorf("ColorFor(%v) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValueGradientErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		stops []ColorStop
+	}{
+		{name: "no stops", stops: []ColorStop{}},
+		{name: "invalid color", stops: []ColorStop{{Value: 0, Color: "red"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewValueGradient(tt.stops, easings["linear"], blends["rgb"]); err == nil {
+				t.Error("NewValueGradient did not fail")
+			}
+		})
+	}
+}
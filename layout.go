@@ -0,0 +1,331 @@
+package auroraops
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ngerakines/auroraops/client"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	tomb "gopkg.in/tomb.v2"
+)
+
+// LayoutDiff describes how the panels of an aurora changed. When the new
+// layout has the same shape as the old one but its panels have new IDs, only
+// Remap is set, mapping each old ID to its new one.
+type LayoutDiff struct {
+	Added   []*client.Panel
+	Removed []*client.Panel
+	Moved   []PanelMove
+	Remap   map[int]int
+}
+
+// PanelMove is a panel that kept its ID but changed position or rotation.
+type PanelMove struct {
+	From *client.Panel
+	To   *client.Panel
+}
+
+// Empty returns true when the layout did not change.
+func (d LayoutDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Moved) == 0 && len(d.Remap) == 0
+}
+
+// Lines describes each change on its own line.
+func (d LayoutDiff) Lines() []string {
+	lines := []string{}
+	for _, panel := range d.Added {
+		lines = append(lines, fmt.Sprintf("panel %d was added at %s", panel.ID, position(panel)))
+	}
+	for _, panel := range d.Removed {
+		lines = append(lines, fmt.Sprintf("panel %d was removed from %s", panel.ID, position(panel)))
+	}
+	for _, move := range d.Moved {
+		lines = append(lines, fmt.Sprintf("panel %d moved from %s to %s", move.From.ID, position(move.From), position(move.To)))
+	}
+	ids := make([]int, 0, len(d.Remap))
+	for id := range d.Remap {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		if d.Remap[id] != id {
+			lines = append(lines, fmt.Sprintf("panel %d is now panel %d", id, d.Remap[id]))
+		}
+	}
+	return lines
+}
+
+func position(panel *client.Panel) string {
+	return fmt.Sprintf("(%d, %d) rotated %d", panel.X, panel.Y, panel.Rotation)
+}
+
+// DiffLayouts compares two layouts of an aurora. A layout with the same shape
+// as the previous one, possibly shifted, is remapped panel by panel when the
+// IDs changed; otherwise panels are compared by ID.
+func DiffLayouts(previous, current []*client.Panel) LayoutDiff {
+	diff := LayoutDiff{}
+	if remap, ok := congruent(previous, current); ok {
+		for from, to := range remap {
+			if from != to {
+				diff.Remap = remap
+				return diff
+			}
+		}
+	}
+
+	previousByID := make(map[int]*client.Panel)
+	for _, panel := range previous {
+		previousByID[panel.ID] = panel
+	}
+	currentByID := make(map[int]*client.Panel)
+	for _, panel := range current {
+		currentByID[panel.ID] = panel
+	}
+	for _, panel := range previous {
+		now, ok := currentByID[panel.ID]
+		if !ok {
+			diff.Removed = append(diff.Removed, panel)
+			continue
+		}
+		if now.X != panel.X || now.Y != panel.Y || now.Rotation != panel.Rotation {
+			diff.Moved = append(diff.Moved, PanelMove{From: panel, To: now})
+		}
+	}
+	for _, panel := range current {
+		if _, ok := previousByID[panel.ID]; !ok {
+			diff.Added = append(diff.Added, panel)
+		}
+	}
+	return diff
+}
+
+// congruent matches the panels of two layouts by position once their centers
+// are lined up, returning the ID each previous panel has now.
+func congruent(previous, current []*client.Panel) (map[int]int, bool) {
+	if len(previous) == 0 || len(previous) != len(current) {
+		return nil, false
+	}
+	center := func(panels []*client.Panel) (float64, float64) {
+		x, y := 0.0, 0.0
+		for _, panel := range panels {
+			x = x + float64(panel.X)
+			y = y + float64(panel.Y)
+		}
+		return x / float64(len(panels)), y / float64(len(panels))
+	}
+	previousX, previousY := center(previous)
+	currentX, currentY := center(current)
+	dx, dy := currentX-previousX, currentY-previousY
+
+	sideLength := 0
+	for _, panel := range previous {
+		if panel.SideLength > sideLength {
+			sideLength = panel.SideLength
+		}
+	}
+	if sideLength == 0 {
+		sideLength = 150
+	}
+	tolerance := float64(sideLength) / 4
+
+	remap := make(map[int]int)
+	used := make(map[int]bool)
+	for _, panel := range previous {
+		found := false
+		for _, now := range current {
+			if used[now.ID] || (now.Rotation-panel.Rotation)%360 != 0 {
+				continue
+			}
+			if math.Abs(float64(now.X)-float64(panel.X)-dx) > tolerance || math.Abs(float64(now.Y)-float64(panel.Y)-dy) > tolerance {
+				continue
+			}
+			remap[panel.ID] = now.ID
+			used[now.ID] = true
+			found = true
+			break
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return remap, true
+}
+
+// SaveLayout writes a snapshot of a layout to a file, in the form read by
+// LoadLayout and by client.ParseHardwareInfo.
+func SaveLayout(file string, panels []*client.Panel) error {
+	data, err := json.MarshalIndent(map[string]interface{}{"panels": panels}, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "could not compose layout")
+	}
+	if err := ioutil.WriteFile(file, append(data, '\n'), 0644); err != nil {
+		return errors.Wrapf(err, "could not write %s", file)
+	}
+	return nil
+}
+
+// LoadLayout reads a snapshot of a layout from a file. It returns nil when the
+// file does not exist.
+func LoadLayout(file string) ([]*client.Panel, error) {
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read %s", file)
+	}
+	info, err := client.ParseHardwareInfo(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not parse %s", file)
+	}
+	return info.Panels, nil
+}
+
+// LayoutWatcher checks the layout of the aurora on an interval and compares it
+// with a baseline, which is the saved snapshot or otherwise the layout when the
+// watcher starts. Changes are logged, the panels of things are updated, and
+// when remapping is enabled a layout with the same shape but new panel IDs is
+// remapped so that things keep their panels.
+type LayoutWatcher struct {
+	auroraClient client.AuroraClient
+	thingManager *ThingManager
+	snapshot     string
+	interval     time.Duration
+	remap        bool
+	baseline     []*client.Panel
+	current      []*client.Panel
+	// remapped maps the panel IDs of the configuration to the IDs they have
+	// after every remap so far.
+	remapped map[int]int
+
+	t  tomb.Tomb
+	mu sync.Mutex
+}
+
+// NewLayoutWatcher creates a watcher. When snapshot is set, the baseline is
+// read from it, and saved to it when it does not exist yet.
+func NewLayoutWatcher(auroraClient client.AuroraClient, thingManager *ThingManager, snapshot string, interval time.Duration, remap bool) (*LayoutWatcher, error) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	w := &LayoutWatcher{
+		auroraClient: auroraClient,
+		thingManager: thingManager,
+		snapshot:     snapshot,
+		interval:     interval,
+		remap:        remap,
+	}
+	if snapshot != "" {
+		baseline, err := LoadLayout(snapshot)
+		if err != nil {
+			return nil, err
+		}
+		w.baseline = baseline
+	}
+	return w, nil
+}
+
+// Start checks the layout against the baseline and keeps checking it.
+func (w *LayoutWatcher) Start() error {
+	if err := w.check(); err != nil {
+		return err
+	}
+	w.t.Go(w.loop)
+	return nil
+}
+
+func (w *LayoutWatcher) Stop(ctx context.Context) error {
+	log.WithField("component", "layout").Info("Stopping")
+	w.t.Kill(nil)
+	return w.t.Wait()
+}
+
+func (w *LayoutWatcher) loop() error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := w.check(); err != nil {
+				log.WithError(err).Warn("Could not check panel layout.")
+			}
+		case <-w.t.Dying():
+			return nil
+		}
+	}
+}
+
+func (w *LayoutWatcher) check() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	info, err := w.auroraClient.GetInfo()
+	if err != nil {
+		return errors.Wrap(err, "could not get panel layout")
+	}
+	if w.current != nil && reflect.DeepEqual(w.current, info.Panels) {
+		return nil
+	}
+	if w.baseline == nil {
+		if w.snapshot != "" {
+			if err := SaveLayout(w.snapshot, info.Panels); err != nil {
+				return err
+			}
+			log.WithField("file", w.snapshot).Info("Saved panel layout.")
+		}
+		w.baseline = info.Panels
+		w.current = info.Panels
+		return nil
+	}
+
+	diff := DiffLayouts(w.baseline, info.Panels)
+	for _, line := range diff.Lines() {
+		log.WithField("component", "layout").Warn(line)
+	}
+	baseline, remapped := w.baseline, w.remapped
+	if len(diff.Remap) > 0 {
+		if w.remap {
+			log.Warn("Panel layout has the same shape with new panel IDs, remapping things.")
+			// Later changes are compared with the remapped layout.
+			remapped = composeRemap(remapped, diff.Remap)
+			baseline = info.Panels
+		} else {
+			log.Warn("Panel layout has the same shape with new panel IDs, run auroraops layout diff --remap to update the configuration.")
+		}
+	} else if !diff.Empty() {
+		log.Warn("Panel layout does not match the snapshot.")
+	}
+	// The layout is only taken as seen once things are updated, so that a
+	// failed update is retried on the next check.
+	if err := w.thingManager.UpdateLayout(info.Panels, remapped); err != nil {
+		return err
+	}
+	w.baseline, w.remapped, w.current = baseline, remapped, info.Panels
+	return nil
+}
+
+// composeRemap returns the remap that applies first and then next.
+func composeRemap(first, next map[int]int) map[int]int {
+	composed := make(map[int]int)
+	for from, to := range first {
+		composed[from] = to
+		if again, ok := next[to]; ok {
+			composed[from] = again
+		}
+	}
+	for from, to := range next {
+		if _, ok := composed[from]; !ok {
+			composed[from] = to
+		}
+	}
+	return composed
+}
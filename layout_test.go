@@ -0,0 +1,155 @@
+package auroraops
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ngerakines/auroraops/client"
+)
+
+func TestDiffLayouts(t *testing.T) {
+	previous := []*client.Panel{
+		{ID: 1, X: 0, Y: 0, Rotation: 0},
+		{ID: 2, X: 150, Y: 0, Rotation: 60},
+		{ID: 3, X: 75, Y: 130, Rotation: 0},
+	}
+	tests := []struct {
+		name    string
+		current []*client.Panel
+		want    []string
+	}{
+		{
+			name:    "same layout",
+			current: previous,
+			want:    []string{},
+		},
+		{
+			name: "same shape with new ids",
+			current: []*client.Panel{
+				{ID: 13, X: 75, Y: 130, Rotation: 0},
+				{ID: 11, X: 0, Y: 0, Rotation: 0},
+				{ID: 12, X: 150, Y: 0, Rotation: 60},
+			},
+			want: []string{"panel 1 is now panel 11", "panel 2 is now panel 12", "panel 3 is now panel 13"},
+		},
+		{
+			name: "same shape moved, with new ids",
+			current: []*client.Panel{
+				{ID: 11, X: 1000, Y: 500, Rotation: 0},
+				{ID: 12, X: 1152, Y: 498, Rotation: 420},
+				{ID: 13, X: 1075, Y: 630, Rotation: 0},
+			},
+			want: []string{"panel 1 is now panel 11", "panel 2 is now panel 12", "panel 3 is now panel 13"},
+		},
+		{
+			name: "same shape moved, with the same ids",
+			current: []*client.Panel{
+				{ID: 1, X: 100, Y: 0, Rotation: 0},
+				{ID: 2, X: 250, Y: 0, Rotation: 60},
+				{ID: 3, X: 175, Y: 130, Rotation: 0},
+			},
+			want: []string{
+				"panel 1 moved from (0, 0) rotated 0 to (100, 0) rotated 0",
+				"panel 2 moved from (150, 0) rotated 60 to (250, 0) rotated 60",
+				"panel 3 moved from (75, 130) rotated 0 to (175, 130) rotated 0",
+			},
+		},
+		{
+			name: "panel added",
+			current: []*client.Panel{
+				{ID: 1, X: 0, Y: 0, Rotation: 0},
+				{ID: 2, X: 150, Y: 0, Rotation: 60},
+				{ID: 3, X: 75, Y: 130, Rotation: 0},
+				{ID: 4, X: 225, Y: 130, Rotation: 60},
+			},
+			want: []string{"panel 4 was added at (225, 130) rotated 60"},
+		},
+		{
+			name: "panel removed",
+			current: []*client.Panel{
+				{ID: 1, X: 0, Y: 0, Rotation: 0},
+				{ID: 3, X: 75, Y: 130, Rotation: 0},
+			},
+			want: []string{"panel 2 was removed from (150, 0) rotated 60"},
+		},
+		{
+			name: "panel replaced elsewhere",
+			current: []*client.Panel{
+				{ID: 1, X: 0, Y: 0, Rotation: 0},
+				{ID: 2, X: 150, Y: 0, Rotation: 60},
+				{ID: 4, X: 225, Y: 130, Rotation: 60},
+			},
+			want: []string{
+				"panel 4 was added at (225, 130) rotated 60",
+				"panel 3 was removed from (75, 130) rotated 0",
+			},
+		},
+		{
+			name: "panel turned",
+			current: []*client.Panel{
+				{ID: 1, X: 0, Y: 0, Rotation: 0},
+				{ID: 2, X: 150, Y: 0, Rotation: 180},
+				{ID: 3, X: 75, Y: 130, Rotation: 0},
+			},
+			want: []string{"panel 2 moved from (150, 0) rotated 60 to (150, 0) rotated 180"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := DiffLayouts(previous, tt.current)
+			if got := diff.Lines(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffLayouts() = %#v, want %#v", got, tt.want)
+			}
+			if diff.Empty() != (len(tt.want) == 0) {
+				t.Errorf("Empty() = %v with %d changes", diff.Empty(), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestCongruent(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous []*client.Panel
+		current  []*client.Panel
+		want     map[int]int
+		wantOk   bool
+	}{
+		{
+			name: "no panels",
+		},
+		{
+			name:     "different number of panels",
+			previous: []*client.Panel{{ID: 1}},
+			current:  []*client.Panel{{ID: 1}, {ID: 2, X: 150}},
+		},
+		{
+			name:     "shifted within tolerance",
+			previous: []*client.Panel{{ID: 1}, {ID: 2, X: 150}},
+			current:  []*client.Panel{{ID: 5, X: 30, Y: 10}, {ID: 6, X: 190, Y: 0}},
+			want:     map[int]int{1: 5, 2: 6},
+			wantOk:   true,
+		},
+		{
+			name:     "different shape",
+			previous: []*client.Panel{{ID: 1}, {ID: 2, X: 150}},
+			current:  []*client.Panel{{ID: 1}, {ID: 2, Y: 150}},
+		},
+		{
+			name:     "different rotation",
+			previous: []*client.Panel{{ID: 1}, {ID: 2, X: 150, Rotation: 60}},
+			current:  []*client.Panel{{ID: 1}, {ID: 2, X: 150, Rotation: 120}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := congruent(tt.previous, tt.current)
+			if ok != tt.wantOk {
+				t.Fatalf("congruent() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if ok && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("congruent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
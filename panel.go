@@ -1,19 +1,38 @@
 package auroraops
 
-import "time"
+import (
+	"context"
+	"sync"
+	"time"
+)
 
 type panelGroupState struct {
 	status    string
+	value     float64
 	updatedAt time.Time
 }
 
 type panelGroup struct {
 	thing        string
 	panels       []int
+	priority     int
+	opacity      float64
+	layer        *layer
 	currentState panelGroupState
 	action       Action
+	transition   *transition
 	onStart      string
 	onStop       string
+	ack          *acknowledgement
+
+	mu sync.Mutex
+}
+
+// acknowledgement calms a thing's status until the status changes or, when
+// there is a timer, the acknowledgement expires.
+type acknowledgement struct {
+	status string
+	timer  *time.Timer
 }
 
 func (pg *panelGroup) start() error {
@@ -23,3 +42,40 @@ func (pg *panelGroup) start() error {
 func (pg *panelGroup) stop() error {
 	return nil
 }
+
+func (pg *panelGroup) clearAcknowledgement() {
+	if pg.ack != nil && pg.ack.timer != nil {
+		pg.ack.timer.Stop()
+	}
+	pg.ack = nil
+}
+
+// beginTransition mixes the outgoing action into the panel group's layer until
+// the transition has run its course.
+func (pg *panelGroup) beginTransition(t *transition) {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+
+	pg.transition = t
+	pg.layer.setTransition(t)
+	time.AfterFunc(t.duration, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		pg.endTransition(ctx, t)
+	})
+}
+
+// endTransition stops the outgoing action of a transition. Passing nil ends
+// whatever transition is running.
+func (pg *panelGroup) endTransition(ctx context.Context, t *transition) error {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+
+	if pg.transition == nil || (t != nil && pg.transition != t) {
+		return nil
+	}
+	t = pg.transition
+	pg.transition = nil
+	pg.layer.setTransition(nil)
+	return t.action.Stop(ctx)
+}
@@ -0,0 +1,134 @@
+package auroraops
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+
+	colorful "github.com/lucasb-eyer/go-colorful"
+	"github.com/ngerakines/auroraops/client"
+	log "github.com/sirupsen/logrus"
+)
+
+type progressAction struct {
+	panels []int
+	fill   *ValueGradient
+	empty  colorful.Color
+	min    float64
+	max    float64
+	value  float64
+
+	canvas Canvas
+
+	mu sync.Mutex
+}
+
+// NewProgressAction creates an action that lights panels in the given
+// direction, in degrees counter-clockwise from left to right, in proportion
+// to where the value falls between min and max. Lit panels take their color
+// from the fill gradient at the current value.
+func NewProgressAction(canvas Canvas, panels []*client.Panel, fill *ValueGradient, empty colorful.Color, min, max, direction, value float64) (Action, error) {
+	log.Info("New progress action")
+	if !empty.IsValid() {
+		return nil, fmt.Errorf("error: invalid color")
+	}
+	if max <= min {
+		return nil, fmt.Errorf("error: max must be greater than min")
+	}
+	return &progressAction{
+		panels: inDirection(panels, direction),
+		fill:   fill,
+		empty:  empty,
+		min:    min,
+		max:    max,
+		value:  value,
+		canvas: canvas,
+	}, nil
+}
+
+func (a *progressAction) draw() {
+	lit := clamp((a.value-a.min)/(a.max-a.min)) * float64(len(a.panels))
+	fill := a.fill.ColorFor(a.value)
+	for i, panel := range a.panels {
+		// The panel at the edge of the bar is partially lit.
+		amount := clamp(lit - float64(i))
+		a.canvas.SetPanelColor(panel, a.empty.BlendRgb(fill, amount))
+	}
+	log.WithFields(log.Fields{
+		"action": "progress",
+		"value":  a.value,
+		"lit":    math.Round(lit),
+	}).Debug("Drawing")
+}
+
+func (a *progressAction) Start() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.draw()
+	return nil
+}
+
+func (a *progressAction) SetValue(value float64) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.value = value
+	a.draw()
+	return nil
+}
+
+func (a *progressAction) Stop(ctx context.Context) error {
+	log.WithField("action", "progress").Info("Stopping")
+	return nil
+}
+
+// progressConfig defaults to filling from 0 to 100 with Color unless Stops are
+// set.
+type progressConfig struct {
+	Color     string      `mapstructure:"color" schema:"color"`
+	Empty     string      `mapstructure:"empty" schema:"color"`
+	Min       float64     `mapstructure:"min"`
+	Max       float64     `mapstructure:"max"`
+	Stops     []ColorStop `mapstructure:"stops"`
+	Direction float64     `mapstructure:"direction"`
+	Easing    string      `mapstructure:"easing"`
+	Blend     string      `mapstructure:"blend"`
+}
+
+func init() {
+	RegisterAction("progress", newProgressFromConfig, progressConfig{})
+}
+
+func newProgressFromConfig(config map[string]interface{}, ac ActionContext) (Action, error) {
+	pc := progressConfig{}
+	if err := ac.DecodeConfig(config, &pc); err != nil {
+		return nil, err
+	}
+	empty, err := colorOrDefault(pc.Empty, colorful.Color{})
+	if err != nil {
+		return nil, err
+	}
+	min, max := pc.Min, pc.Max
+	if min == 0 && max == 0 {
+		max = 100
+	}
+	stops := pc.Stops
+	if len(stops) == 0 {
+		stops = []ColorStop{{Value: min, Color: pc.Color}}
+	}
+	easing, err := EasingFor(pc.Easing)
+	if err != nil {
+		return nil, err
+	}
+	blend, err := BlendFor(pc.Blend)
+	if err != nil {
+		return nil, err
+	}
+	fill, err := NewValueGradient(stops, easing, blend)
+	if err != nil {
+		return nil, err
+	}
+	return NewProgressAction(ac.Canvas, ac.Geometry, fill, empty, min, max, pc.Direction, ac.Value)
+}
@@ -0,0 +1,134 @@
+package auroraops
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ngerakines/auroraops/client"
+)
+
+func TestProgressAction(t *testing.T) {
+	row := []*client.Panel{{ID: 1, X: 0}, {ID: 2, X: 100}, {ID: 3, X: 200}, {ID: 4, X: 300}}
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		value  float64
+		want   map[int]string
+	}{
+		{
+			name:   "fills in proportion to the value",
+			config: map[string]interface{}{"color": "#ffffff"},
+			value:  50,
+			want:   map[int]string{1: "#ffffff", 2: "#ffffff", 3: "#000000", 4: "#000000"},
+		},
+		{
+			name:   "partially lights the panel at the edge",
+			config: map[string]interface{}{"color": "#ffffff"},
+			value:  62.5,
+			want:   map[int]string{1: "#ffffff", 2: "#ffffff", 3: "#808080", 4: "#000000"},
+		},
+		{
+			name:   "fills in its direction",
+			config: map[string]interface{}{"color": "#ffffff", "direction": 180},
+			value:  25,
+			want:   map[int]string{1: "#000000", 2: "#000000", 3: "#000000", 4: "#ffffff"},
+		},
+		{
+			name:   "fills between min and max",
+			config: map[string]interface{}{"color": "#ffffff", "min": 10, "max": 20},
+			value:  17.5,
+			want:   map[int]string{1: "#ffffff", 2: "#ffffff", 3: "#ffffff", 4: "#000000"},
+		},
+		{
+			name:   "fills every panel above max",
+			config: map[string]interface{}{"color": "#ffffff"},
+			value:  150,
+			want:   map[int]string{1: "#ffffff", 2: "#ffffff", 3: "#ffffff", 4: "#ffffff"},
+		},
+		{
+			name:   "leaves panels empty below min",
+			config: map[string]interface{}{"color": "#ffffff", "empty": "#0000ff"},
+			value:  -10,
+			want:   map[int]string{1: "#0000ff", 2: "#0000ff", 3: "#0000ff", 4: "#0000ff"},
+		},
+		{
+			name: "takes the fill from the stops at the value",
+			config: map[string]interface{}{
+				"stops": []interface{}{
+					map[string]interface{}{"value": 0, "color": "#ff0000"},
+					map[string]interface{}{"value": 100, "color": "#00ff00"},
+				},
+				"blend": "rgb",
+			},
+			value: 50,
+			want:  map[int]string{1: "#808000", 2: "#808000", 3: "#000000", 4: "#000000"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			canvas := recordingCanvas{}
+			action, err := newAction(StatusConfigSet{Type: "progress", Config: tt.config}, ActionContext{
+				Value:    tt.value,
+				Geometry: row,
+				Canvas:   canvas,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := action.Start(); err != nil {
+				t.Fatal(err)
+			}
+			if got := canvas.hexes(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("colors = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProgressSetValue(t *testing.T) {
+	canvas := recordingCanvas{}
+	action, err := newAction(StatusConfigSet{Type: "progress", Config: map[string]interface{}{"color": "#ffffff", "max": 4}}, ActionContext{
+		Geometry: []*client.Panel{{ID: 1, X: 0}, {ID: 2, X: 100}, {ID: 3, X: 200}, {ID: 4, X: 300}},
+		Canvas:   canvas,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := action.Start(); err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		value float64
+		want  map[int]string
+	}{
+		{value: 1, want: map[int]string{1: "#ffffff", 2: "#000000", 3: "#000000", 4: "#000000"}},
+		{value: 3, want: map[int]string{1: "#ffffff", 2: "#ffffff", 3: "#ffffff", 4: "#000000"}},
+		{value: 2, want: map[int]string{1: "#ffffff", 2: "#ffffff", 3: "#000000", 4: "#000000"}},
+	}
+	for _, step := range steps {
+		if err := action.(ValueAction).SetValue(step.value); err != nil {
+			t.Fatal(err)
+		}
+		if got := canvas.hexes(); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("colors at %v = %v, want %v", step.value, got, step.want)
+		}
+	}
+}
+
+func TestProgressErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+	}{
+		{name: "invalid color", config: map[string]interface{}{"color": "bright"}},
+		{name: "invalid empty color", config: map[string]interface{}{"color": "#ffffff", "empty": "dark"}},
+		{name: "max below min", config: map[string]interface{}{"color": "#ffffff", "min": 10, "max": 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newAction(StatusConfigSet{Type: "progress", Config: tt.config}, ActionContext{Canvas: recordingCanvas{}}); err == nil {
+				t.Errorf("newAction(%v) succeeded, want an error", tt.config)
+			}
+		})
+	}
+}
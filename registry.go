@@ -0,0 +1,187 @@
+package auroraops
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/ngerakines/auroraops/client"
+	"github.com/pkg/errors"
+)
+
+// ActionContext is everything an action factory is given, besides the status
+// configuration, to create an action for a thing.
+type ActionContext struct {
+	// Status is the name of the status being drawn and Value is the number
+	// the thing was given, if any.
+	Status string
+	Value  float64
+
+	// Panels are the thing's panels, and Geometry is their layout. Layout
+	// has every panel on the aurora.
+	Panels   []int
+	Geometry []*client.Panel
+	Layout   map[int]*client.Panel
+
+	// Devices are the configured auroras, against which references to panels
+	// such as "hall:13" are resolved.
+	Devices []DeviceConfig
+
+	// Canvas is where the action draws. Client is the aurora itself, for
+	// actions that need more than panel colors.
+	Canvas Canvas
+	Client client.AuroraClient
+}
+
+// ActionFactory creates an action from the configuration of a status. The
+// configuration excludes the type and transition of the status.
+type ActionFactory func(config map[string]interface{}, ac ActionContext) (Action, error)
+
+type actionType struct {
+	factory ActionFactory
+	schema  reflect.Type
+}
+
+var (
+	actionTypesMu sync.RWMutex
+	actionTypes   = make(map[string]actionType)
+)
+
+// RegisterAction makes an action type available to statuses by name. Schema
+// is the struct, or a pointer to the struct, that the type's configuration
+// decodes into. It is used to check configuration before any action is
+// created. RegisterAction is meant to be called from init functions and panics
+// if the name is already registered.
+func RegisterAction(name string, factory ActionFactory, schema interface{}) {
+	actionTypesMu.Lock()
+	defer actionTypesMu.Unlock()
+
+	if factory == nil {
+		panic("auroraops: RegisterAction factory is nil")
+	}
+	if _, dup := actionTypes[name]; dup {
+		panic("auroraops: RegisterAction called twice for " + name)
+	}
+	t := reflect.TypeOf(schema)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		panic("auroraops: RegisterAction schema for " + name + " is not a struct")
+	}
+	actionTypes[name] = actionType{factory, t}
+}
+
+// ActionTypes returns the names of the registered action types.
+func ActionTypes() []string {
+	actionTypesMu.RLock()
+	defer actionTypesMu.RUnlock()
+
+	names := make([]string, 0, len(actionTypes))
+	for name := range actionTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupActionType(name string) (actionType, error) {
+	actionTypesMu.RLock()
+	defer actionTypesMu.RUnlock()
+
+	at, ok := actionTypes[name]
+	if !ok {
+		return actionType{}, fmt.Errorf("unsupported status type: %s", name)
+	}
+	return at, nil
+}
+
+// newAction creates the action for a status with its registered factory.
+func newAction(statusConfig StatusConfigSet, ac ActionContext) (Action, error) {
+	at, err := lookupActionType(statusConfig.Type)
+	if err != nil {
+		return nil, err
+	}
+	return at.factory(statusConfig.Config, ac)
+}
+
+// checkStatusConfig makes sure that a status has a registered type and that
+// its configuration decodes into the type's schema.
+func checkStatusConfig(statusConfig StatusConfigSet, devices []DeviceConfig) error {
+	at, err := lookupActionType(statusConfig.Type)
+	if err != nil {
+		return err
+	}
+	target := reflect.New(at.schema).Interface()
+	return ActionContext{Devices: devices}.DecodeConfig(statusConfig.Config, target)
+}
+
+// DecodeActionConfig decodes the configuration of a status into an action
+// type's configuration struct. Durations can be given as strings such as
+// "1500ms", and keys that the struct does not have are rejected. Panels of
+// named devices cannot be referred to; use ActionContext.DecodeConfig for
+// that.
+func DecodeActionConfig(config map[string]interface{}, target interface{}) error {
+	return decodeConfig(config, target, true)
+}
+
+// DecodeConfig decodes the configuration of a status like DecodeActionConfig,
+// and also reads references to panels of named devices, such as "hall:13",
+// into panel IDs.
+func (ac ActionContext) DecodeConfig(config map[string]interface{}, target interface{}) error {
+	return decodeConfig(config, target, true, panelRefHookFunc(ac.Devices))
+}
+
+// configKey returns a key of a YAML map as a string. YAML 1.1 reads the keys
+// on and off as booleans, which would otherwise become "true" and "false".
+func configKey(key interface{}) string {
+	if b, ok := key.(bool); ok {
+		if b {
+			return "on"
+		}
+		return "off"
+	}
+	return fmt.Sprint(key)
+}
+
+func stringKeysHookFunc(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	entries, ok := data.(map[interface{}]interface{})
+	if !ok {
+		return data, nil
+	}
+	converted := make(map[string]interface{}, len(entries))
+	for key, entry := range entries {
+		converted[configKey(key)] = entry
+	}
+	return converted, nil
+}
+
+func decodeConfig(input, target interface{}, strict bool, hooks ...mapstructure.DecodeHookFunc) error {
+	hooks = append([]mapstructure.DecodeHookFunc{
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+		stringKeysHookFunc,
+	}, hooks...)
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:           target,
+		WeaklyTypedInput: true,
+		ErrorUnused:      strict,
+		DecodeHook:       mapstructure.ComposeDecodeHookFunc(hooks...),
+	})
+	if err != nil {
+		return err
+	}
+	if err := decoder.Decode(input); err != nil {
+		// Decoding errors are reported one per line, relative to an unnamed
+		// root, which reads poorly inside a single log line.
+		if decodeErr, ok := err.(*mapstructure.Error); ok {
+			message := strings.Replace(strings.Join(decodeErr.Errors, "; "), "'' ", "", -1)
+			return fmt.Errorf("invalid configuration: %s", message)
+		}
+		return errors.Wrap(err, "invalid configuration")
+	}
+	return nil
+}
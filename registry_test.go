@@ -0,0 +1,170 @@
+package auroraops
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	colorful "github.com/lucasb-eyer/go-colorful"
+)
+
+type testActionConfig struct {
+	Color    string        `mapstructure:"color"`
+	Duration time.Duration `mapstructure:"duration"`
+	Panels   []int         `mapstructure:"panels"`
+}
+
+// registerTestAction registers an action type called test that records the
+// configuration its factory is given. The returned function unregisters it.
+func registerTestAction() (*testActionConfig, func()) {
+	created := &testActionConfig{}
+	RegisterAction("test", func(config map[string]interface{}, ac ActionContext) (Action, error) {
+		if err := ac.DecodeConfig(config, created); err != nil {
+			return nil, err
+		}
+		color, err := colorful.Hex(created.Color)
+		if err != nil {
+			return nil, err
+		}
+		return NewSolidFillAction(ac.Canvas, ac.Panels, color)
+	}, testActionConfig{})
+	return created, func() {
+		actionTypesMu.Lock()
+		delete(actionTypes, "test")
+		actionTypesMu.Unlock()
+	}
+}
+
+func TestRegisterAction(t *testing.T) {
+	created, unregister := registerTestAction()
+	defer unregister()
+
+	found := false
+	for _, name := range ActionTypes() {
+		found = found || name == "test"
+	}
+	if !found {
+		t.Errorf("ActionTypes() = %v, want test", ActionTypes())
+	}
+
+	devices := []DeviceConfig{{Name: "hall", URL: "http://hall"}, {Name: "lobby", URL: "http://lobby"}}
+	statusConfig := StatusConfigSet{
+		Type:   "test",
+		Config: map[string]interface{}{"color": "#ff0000", "duration": "1500ms", "panels": []interface{}{1, "lobby:2"}},
+	}
+	if err := checkStatusConfig(statusConfig, devices); err != nil {
+		t.Fatalf("checkStatusConfig() error = %v", err)
+	}
+	canvas := recordingCanvas{}
+	action, err := newAction(statusConfig, ActionContext{Panels: []int{1}, Canvas: canvas, Devices: devices})
+	if err != nil {
+		t.Fatalf("newAction() error = %v", err)
+	}
+	if err := action.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := canvas.hexes(), map[int]string{1: "#ff0000"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("colors = %v, want %v", got, want)
+	}
+	if created.Duration != 1500*time.Millisecond {
+		t.Errorf("duration = %s, want 1.5s", created.Duration)
+	}
+	if want := []int{1, devicePanelSpan + 2}; !reflect.DeepEqual(created.Panels, want) {
+		t.Errorf("panels = %v, want %v", created.Panels, want)
+	}
+}
+
+func TestRegisterActionPanics(t *testing.T) {
+	_, unregister := registerTestAction()
+	defer unregister()
+	factory := func(config map[string]interface{}, ac ActionContext) (Action, error) {
+		return nil, nil
+	}
+	tests := []struct {
+		name      string
+		action    string
+		factory   ActionFactory
+		schema    interface{}
+		wantPanic string
+	}{
+		{
+			name:      "names registered twice",
+			action:    "test",
+			factory:   factory,
+			schema:    testActionConfig{},
+			wantPanic: "auroraops: RegisterAction called twice for test",
+		},
+		{
+			name:      "factories that are nil",
+			action:    "other",
+			schema:    testActionConfig{},
+			wantPanic: "auroraops: RegisterAction factory is nil",
+		},
+		{
+			name:      "schemas that are not structs",
+			action:    "other",
+			factory:   factory,
+			schema:    map[string]interface{}{},
+			wantPanic: "auroraops: RegisterAction schema for other is not a struct",
+		},
+		{
+			name:      "schemas that are nil",
+			action:    "other",
+			factory:   factory,
+			wantPanic: "auroraops: RegisterAction schema for other is not a struct",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if got := recover(); got != tt.wantPanic {
+					t.Errorf("RegisterAction() panic = %v, want %q", got, tt.wantPanic)
+				}
+			}()
+			RegisterAction(tt.action, tt.factory, tt.schema)
+		})
+	}
+	if _, err := lookupActionType("other"); err == nil {
+		t.Error("other was registered after panicking")
+	}
+}
+
+func TestCheckStatusConfig(t *testing.T) {
+	_, unregister := registerTestAction()
+	defer unregister()
+	tests := []struct {
+		name         string
+		statusConfig StatusConfigSet
+		wantErr      string
+	}{
+		{
+			name:         "unknown types",
+			statusConfig: StatusConfigSet{Type: "sparkle"},
+			wantErr:      "unsupported status type: sparkle",
+		},
+		{
+			name:         "unknown keys",
+			statusConfig: StatusConfigSet{Type: "test", Config: map[string]interface{}{"colour": "#ff0000"}},
+			wantErr:      "invalid configuration: has invalid keys: colour",
+		},
+		{
+			name:         "values of the wrong type",
+			statusConfig: StatusConfigSet{Type: "test", Config: map[string]interface{}{"duration": "soon"}},
+			wantErr:      "invalid configuration: error decoding 'duration'",
+		},
+		{
+			name:         "panels of unknown devices",
+			statusConfig: StatusConfigSet{Type: "test", Config: map[string]interface{}{"panels": []interface{}{"attic:1"}}},
+			wantErr:      "unknown device: attic",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkStatusConfig(tt.statusConfig, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkStatusConfig() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
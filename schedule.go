@@ -0,0 +1,398 @@
+package auroraops
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ngerakines/auroraops/client"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	tomb "gopkg.in/tomb.v2"
+)
+
+// ScheduleConfig describes a window of time during which the aurora is
+// restricted. Days lists the days the window starts on, such as "mon" or
+// "mon-fri", and defaults to every day. Start and End are times of day such as
+// "22:00"; a window that ends before it starts runs past midnight. During the
+// window brightness is capped to Brightness (0 to 1), Still draws animated
+// statuses as solid colors and Off turns the aurora off.
+type ScheduleConfig struct {
+	Name       string   `mapstructure:"name"`
+	Days       []string `mapstructure:"days"`
+	Start      string   `mapstructure:"start"`
+	End        string   `mapstructure:"end"`
+	Brightness float64  `mapstructure:"brightness"`
+	Still      bool     `mapstructure:"still"`
+	Off        bool     `mapstructure:"off"`
+}
+
+// ParseScheduleConfig reads the schedules section of the configuration.
+func ParseScheduleConfig(raw interface{}) ([]ScheduleConfig, error) {
+	schedules := []ScheduleConfig{}
+	if err := decodeConfig(raw, &schedules, true); err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// stillTypes are the action types that do not animate, and are drawn as they
+// are while animations are suppressed.
+var stillTypes = map[string]bool{
+	"solid":    true,
+	"clear":    true,
+	"gradient": true,
+	"progress": true,
+}
+
+type window struct {
+	name       string
+	days       [7]bool
+	start      time.Duration
+	end        time.Duration
+	brightness float64
+	still      bool
+	off        bool
+}
+
+// restrictions are what the windows that are open at a moment ask for.
+type restrictions struct {
+	brightness float64
+	still      bool
+	off        bool
+}
+
+// Scheduler applies quiet hours to the aurora. It checks its windows every
+// minute and whenever a thing changes status, lifting every restriction while
+// any thing has a critical status.
+type Scheduler struct {
+	auroraClient client.AuroraClient
+	thingManager *ThingManager
+	compositor   *Compositor
+	windows      []window
+	brightness   int
+	applied      restrictions
+
+	t  tomb.Tomb
+	mu sync.Mutex
+}
+
+func NewScheduler(auroraClient client.AuroraClient, thingManager *ThingManager, compositor *Compositor, schedules []ScheduleConfig) (*Scheduler, error) {
+	windows := make([]window, len(schedules))
+	for i, schedule := range schedules {
+		w, err := newWindow(schedule)
+		if err != nil {
+			name := schedule.Name
+			if name == "" {
+				name = fmt.Sprintf("%d", i)
+			}
+			return nil, errors.Wrapf(err, "schedule %s", name)
+		}
+		windows[i] = w
+	}
+	return &Scheduler{
+		auroraClient: auroraClient,
+		thingManager: thingManager,
+		compositor:   compositor,
+		windows:      windows,
+		applied:      restrictions{brightness: 1},
+	}, nil
+}
+
+func newWindow(schedule ScheduleConfig) (window, error) {
+	w := window{
+		name:       schedule.Name,
+		brightness: schedule.Brightness,
+		still:      schedule.Still,
+		off:        schedule.Off,
+	}
+	if w.brightness < 0 || w.brightness > 1 {
+		return w, fmt.Errorf("error: brightness must be between 0 and 1")
+	}
+	var err error
+	if w.start, err = parseTimeOfDay(schedule.Start); err != nil {
+		return w, err
+	}
+	if w.end, err = parseTimeOfDay(schedule.End); err != nil {
+		return w, err
+	}
+	if len(schedule.Days) == 0 {
+		for day := range w.days {
+			w.days[day] = true
+		}
+	}
+	for _, days := range schedule.Days {
+		parts := strings.SplitN(strings.ToLower(strings.TrimSpace(days)), "-", 2)
+		first, ok := weekdays[parts[0]]
+		if !ok {
+			return w, fmt.Errorf("unknown day: %s", days)
+		}
+		last := first
+		if len(parts) == 2 {
+			if last, ok = weekdays[parts[1]]; !ok {
+				return w, fmt.Errorf("unknown day: %s", days)
+			}
+		}
+		for day := first; ; day = (day + 1) % 7 {
+			w.days[day] = true
+			if day == last {
+				break
+			}
+		}
+	}
+	return w, nil
+}
+
+func parseTimeOfDay(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid time of day: %s", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// open returns true when the window is open at the given time.
+func (w window) open(now time.Time) bool {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	offset := now.Sub(midnight)
+	if w.start <= w.end {
+		return w.days[now.Weekday()] && offset >= w.start && offset < w.end
+	}
+	if offset >= w.start {
+		return w.days[now.Weekday()]
+	}
+	return offset < w.end && w.days[(now.Weekday()+6)%7]
+}
+
+// restrictionsAt combines the windows that are open at the given time. The
+// lowest brightness cap wins.
+func (s *Scheduler) restrictionsAt(now time.Time) restrictions {
+	r := restrictions{brightness: 1}
+	for _, w := range s.windows {
+		if !w.open(now) {
+			continue
+		}
+		if w.brightness > 0 {
+			r.brightness = math.Min(r.brightness, w.brightness)
+		}
+		r.still = r.still || w.still
+		r.off = r.off || w.off
+	}
+	return r
+}
+
+// brightnessScaler is implemented by clients that keep the brightness of each
+// of their devices, such as DeviceSet.
+type brightnessScaler interface {
+	ScaleBrightness(scale float64) error
+}
+
+// Start remembers the brightness of the aurora, which is restored when quiet
+// hours end, and starts checking the windows. Clients that keep the brightness
+// of each device remember it themselves.
+func (s *Scheduler) Start() error {
+	if _, ok := s.auroraClient.(brightnessScaler); !ok {
+		info, err := s.auroraClient.GetInfo()
+		if err != nil {
+			return errors.Wrap(err, "could not get brightness")
+		}
+		s.brightness = info.State.Brightness.Value
+		if s.brightness <= 0 {
+			s.brightness = 100
+		}
+	}
+	s.t.Go(s.loop)
+	return nil
+}
+
+// Stop ends the schedule, turning the aurora back on at its full brightness.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	log.WithField("component", "scheduler").Info("Stopping")
+	s.t.Kill(nil)
+	if err := s.t.Wait(); err != nil {
+		return err
+	}
+	return s.apply(restrictions{brightness: 1})
+}
+
+func (s *Scheduler) loop() error {
+	s.check()
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.check()
+		case <-s.thingManager.Changed():
+			s.check()
+		case <-s.t.Dying():
+			return nil
+		}
+	}
+}
+
+func (s *Scheduler) check() {
+	r := restrictions{brightness: 1}
+	if !s.thingManager.Critical() {
+		r = s.restrictionsAt(time.Now())
+	}
+	if err := s.apply(r); err != nil {
+		log.WithError(err).Error("Could not apply schedule.")
+	}
+}
+
+// apply changes what differs from the restrictions that were last applied.
+func (s *Scheduler) apply(r restrictions) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r == s.applied {
+		return nil
+	}
+	log.WithFields(log.Fields{
+		"brightness": r.brightness,
+		"still":      r.still,
+		"off":        r.off,
+	}).Info("Applying schedule.")
+	if r.off != s.applied.off {
+		if err := s.auroraClient.SetPower(!r.off); err != nil {
+			return err
+		}
+		if !r.off {
+			s.compositor.Invalidate()
+		}
+		s.applied.off = r.off
+	}
+	if r.brightness != s.applied.brightness {
+		if err := s.scaleBrightness(r.brightness); err != nil {
+			return err
+		}
+		s.applied.brightness = r.brightness
+	}
+	if r.still != s.applied.still {
+		if err := s.thingManager.SetStill(r.still); err != nil {
+			return err
+		}
+		s.applied.still = r.still
+	}
+	return nil
+}
+
+// scaleBrightness sets the aurora to a fraction of the brightness it had.
+func (s *Scheduler) scaleBrightness(scale float64) error {
+	if scaler, ok := s.auroraClient.(brightnessScaler); ok {
+		return scaler.ScaleBrightness(scale)
+	}
+	return s.auroraClient.SetBrightness(int(math.Round(float64(s.brightness) * scale)))
+}
+
+// stillStatus returns the status drawn in place of an animated one while
+// animations are suppressed: a solid fill of the color that stands for it, the
+// first frame of a script, or nothing when neither can be found.
+func stillStatus(statusConfig StatusConfigSet) StatusConfigSet {
+	if stillTypes[statusConfig.Type] {
+		return statusConfig
+	}
+	if statusConfig.Type == "script" {
+		still := StatusConfigSet{
+			Type:       "script",
+			Transition: statusConfig.Transition,
+			Config:     map[string]interface{}{"still": true},
+		}
+		for key, value := range statusConfig.Config {
+			if key != "still" {
+				still.Config[key] = value
+			}
+		}
+		return still
+	}
+	still := StatusConfigSet{
+		Type:       "clear",
+		Transition: statusConfig.Transition,
+	}
+	if color, ok := stillColor(statusConfig); ok {
+		still.Type = "solid"
+		still.Config = map[string]interface{}{"color": color}
+	}
+	return still
+}
+
+// stillColor returns the color that stands for an animated status: its color,
+// the first color of its earliest keyframe, or the middle of its stops. Cycles
+// and fires without them use their default red and warm palette.
+func stillColor(statusConfig StatusConfigSet) (interface{}, bool) {
+	if color, ok := statusConfig.Config["color"]; ok {
+		return color, true
+	}
+	if raw, ok := statusConfig.Config["keyframes"]; ok {
+		keyframes := []Keyframe{}
+		if err := decodeConfig(raw, &keyframes, false); err != nil || len(keyframes) == 0 {
+			return nil, false
+		}
+		sort.SliceStable(keyframes, func(i, j int) bool {
+			return keyframes[i].Offset < keyframes[j].Offset
+		})
+		return keyframeColor(keyframes[0])
+	}
+	stops := []ColorStop{}
+	if raw, ok := statusConfig.Config["stops"]; ok {
+		if err := decodeConfig(raw, &stops, false); err != nil {
+			return nil, false
+		}
+	}
+	switch {
+	case len(stops) > 0:
+	case statusConfig.Type == "fire":
+		stops = firePalette
+	case statusConfig.Type == "cycle":
+		return "#ff0000", true
+	default:
+		return nil, false
+	}
+	easing, _ := EasingFor("")
+	blend, _ := BlendFor("")
+	gradient, err := NewValueGradient(stops, easing, blend)
+	if err != nil {
+		return nil, false
+	}
+	return gradient.ColorFor((gradient.min + gradient.max) / 2).Hex(), true
+}
+
+// keyframeColor returns the color of a keyframe, or of its first group or
+// panel when it sets no color for every panel.
+func keyframeColor(keyframe Keyframe) (interface{}, bool) {
+	if keyframe.Color != "" {
+		return keyframe.Color, true
+	}
+	groups := make([]string, 0, len(keyframe.Groups))
+	for group := range keyframe.Groups {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	if len(groups) > 0 {
+		return keyframe.Groups[groups[0]], true
+	}
+	panels := make([]int, 0, len(keyframe.Panels))
+	for panel := range keyframe.Panels {
+		panels = append(panels, panel)
+	}
+	sort.Ints(panels)
+	if len(panels) > 0 {
+		return keyframe.Panels[panels[0]], true
+	}
+	return nil, false
+}
@@ -0,0 +1,233 @@
+package auroraops
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWindowOpen(t *testing.T) {
+	// 2024-01-01 is a Monday.
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 1, day, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name     string
+		schedule ScheduleConfig
+		now      time.Time
+		want     bool
+	}{
+		{
+			name:     "open during the day",
+			schedule: ScheduleConfig{Start: "09:00", End: "17:00"},
+			now:      at(1, 12, 0),
+			want:     true,
+		},
+		{
+			name:     "open from its start",
+			schedule: ScheduleConfig{Start: "09:00", End: "17:00"},
+			now:      at(1, 9, 0),
+			want:     true,
+		},
+		{
+			name:     "closed at its end",
+			schedule: ScheduleConfig{Start: "09:00", End: "17:00"},
+			now:      at(1, 17, 0),
+			want:     false,
+		},
+		{
+			name:     "open before midnight overnight",
+			schedule: ScheduleConfig{Start: "22:00", End: "07:00"},
+			now:      at(1, 23, 30),
+			want:     true,
+		},
+		{
+			name:     "open after midnight overnight",
+			schedule: ScheduleConfig{Start: "22:00", End: "07:00"},
+			now:      at(2, 6, 59),
+			want:     true,
+		},
+		{
+			name:     "closed in the middle of the day overnight",
+			schedule: ScheduleConfig{Start: "22:00", End: "07:00"},
+			now:      at(2, 12, 0),
+			want:     false,
+		},
+		{
+			name:     "closed on other days",
+			schedule: ScheduleConfig{Days: []string{"sat", "sun"}, Start: "09:00", End: "17:00"},
+			now:      at(1, 12, 0),
+			want:     false,
+		},
+		{
+			name:     "open on days in a range",
+			schedule: ScheduleConfig{Days: []string{"mon-fri"}, Start: "09:00", End: "17:00"},
+			now:      at(3, 12, 0),
+			want:     true,
+		},
+		{
+			name:     "open on days in a range past the weekend",
+			schedule: ScheduleConfig{Days: []string{"fri-mon"}, Start: "09:00", End: "17:00"},
+			now:      at(7, 12, 0),
+			want:     true,
+		},
+		{
+			name:     "closed on days outside a range past the weekend",
+			schedule: ScheduleConfig{Days: []string{"fri-mon"}, Start: "09:00", End: "17:00"},
+			now:      at(2, 12, 0),
+			want:     false,
+		},
+		{
+			name:     "open past midnight into a day not listed",
+			schedule: ScheduleConfig{Days: []string{"fri"}, Start: "22:00", End: "07:00"},
+			now:      at(6, 3, 0),
+			want:     true,
+		},
+		{
+			name:     "closed past midnight after a day not listed",
+			schedule: ScheduleConfig{Days: []string{"fri"}, Start: "22:00", End: "07:00"},
+			now:      at(5, 3, 0),
+			want:     false,
+		},
+		{
+			name:     "matches days regardless of case",
+			schedule: ScheduleConfig{Days: []string{"Mon"}, Start: "09:00", End: "17:00"},
+			now:      at(1, 12, 0),
+			want:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := newWindow(tt.schedule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := w.open(tt.now); got != tt.want {
+				t.Errorf("open(%s) = %v, want %v", tt.now.Format("Mon 15:04"), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewWindowErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule ScheduleConfig
+	}{
+		{name: "invalid start", schedule: ScheduleConfig{Start: "25:00", End: "07:00"}},
+		{name: "missing end", schedule: ScheduleConfig{Start: "22:00"}},
+		{name: "unknown day", schedule: ScheduleConfig{Days: []string{"someday"}, Start: "22:00", End: "07:00"}},
+		{name: "unknown last day", schedule: ScheduleConfig{Days: []string{"mon-someday"}, Start: "22:00", End: "07:00"}},
+		{name: "brightness out of range", schedule: ScheduleConfig{Start: "22:00", End: "07:00", Brightness: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newWindow(tt.schedule); err == nil {
+				t.Error("newWindow did not fail")
+			}
+		})
+	}
+}
+
+func TestRestrictionsAt(t *testing.T) {
+	s, err := NewScheduler(nil, nil, nil, []ScheduleConfig{
+		{Name: "evening", Start: "20:00", End: "23:00", Brightness: 0.5},
+		{Name: "night", Start: "22:00", End: "07:00", Brightness: 0.2, Still: true},
+		{Name: "late", Start: "01:00", End: "05:00", Off: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		hour int
+		want restrictions
+	}{
+		{hour: 12, want: restrictions{brightness: 1}},
+		{hour: 21, want: restrictions{brightness: 0.5}},
+		{hour: 22, want: restrictions{brightness: 0.2, still: true}},
+		{hour: 3, want: restrictions{brightness: 0.2, still: true, off: true}},
+	}
+	for _, tt := range tests {
+		if got := s.restrictionsAt(time.Date(2024, 1, 1, tt.hour, 0, 0, 0, time.UTC)); got != tt.want {
+			t.Errorf("restrictionsAt(%d:00) = %+v, want %+v", tt.hour, got, tt.want)
+		}
+	}
+}
+
+func TestStillStatus(t *testing.T) {
+	transition := TransitionConfig{Type: "crossfade", Duration: time.Second}
+	tests := []struct {
+		name   string
+		status StatusConfigSet
+		want   StatusConfigSet
+	}{
+		{
+			name:   "keeps statuses that do not animate",
+			status: StatusConfigSet{Type: "gradient", Config: map[string]interface{}{"stops": []interface{}{}}},
+			want:   StatusConfigSet{Type: "gradient", Config: map[string]interface{}{"stops": []interface{}{}}},
+		},
+		{
+			name:   "fills with the color",
+			status: StatusConfigSet{Type: "breath", Transition: transition, Config: map[string]interface{}{"color": "#ff0000", "period": "2s"}},
+			want:   StatusConfigSet{Type: "solid", Transition: transition, Config: map[string]interface{}{"color": "#ff0000"}},
+		},
+		{
+			name: "fills with the color of the earliest keyframe",
+			status: StatusConfigSet{Type: "timeline", Config: map[string]interface{}{
+				"duration": "2s",
+				"keyframes": []interface{}{
+					map[string]interface{}{"offset": "1s", "color": "#0000ff"},
+					map[string]interface{}{"offset": "0s", "color": "#00ff00"},
+				},
+			}},
+			want: StatusConfigSet{Type: "solid", Config: map[string]interface{}{"color": "#00ff00"}},
+		},
+		{
+			name: "fills with the first group of a keyframe",
+			status: StatusConfigSet{Type: "timeline", Config: map[string]interface{}{
+				"keyframes": []interface{}{
+					map[string]interface{}{"groups": map[string]interface{}{"top": "#0000ff", "bottom": "#00ff00"}},
+				},
+			}},
+			want: StatusConfigSet{Type: "solid", Config: map[string]interface{}{"color": "#00ff00"}},
+		},
+		{
+			name: "fills with the middle of the stops",
+			status: StatusConfigSet{Type: "fire", Config: map[string]interface{}{
+				"stops": []interface{}{
+					map[string]interface{}{"value": 0, "color": "#ff0000"},
+					map[string]interface{}{"value": 0.5, "color": "#00ff00"},
+					map[string]interface{}{"value": 1, "color": "#0000ff"},
+				},
+			}},
+			want: StatusConfigSet{Type: "solid", Config: map[string]interface{}{"color": "#00ff00"}},
+		},
+		{
+			name:   "fills fires with the middle of the warm palette",
+			status: StatusConfigSet{Type: "fire", Config: map[string]interface{}{}},
+			want:   StatusConfigSet{Type: "solid", Config: map[string]interface{}{"color": "#b11904"}},
+		},
+		{
+			name:   "fills cycles with their default red",
+			status: StatusConfigSet{Type: "cycle", Config: map[string]interface{}{"period": "10s"}},
+			want:   StatusConfigSet{Type: "solid", Config: map[string]interface{}{"color": "#ff0000"}},
+		},
+		{
+			name:   "draws the first frame of scripts",
+			status: StatusConfigSet{Type: "script", Transition: transition, Config: map[string]interface{}{"script": "wave.star"}},
+			want:   StatusConfigSet{Type: "script", Transition: transition, Config: map[string]interface{}{"script": "wave.star", "still": true}},
+		},
+		{
+			name:   "clears statuses without a color",
+			status: StatusConfigSet{Type: "twinkle", Config: map[string]interface{}{"density": 0.5}},
+			want:   StatusConfigSet{Type: "clear"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stillStatus(tt.status); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stillStatus() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
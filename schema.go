@@ -0,0 +1,384 @@
+package auroraops
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	colorPattern    = `^#([0-9A-Fa-f]{3}|[0-9A-Fa-f]{6})$`
+	durationPattern = `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+	panelPattern    = `^([^:]+:)?[0-9]+$`
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// ConfigSchema returns a JSON Schema (draft-07) describing the configuration
+// file. Statuses are described by the configuration structs of the registered
+// action types, so types registered by other packages are included.
+func ConfigSchema() map[string]interface{} {
+	statusTypes := ActionTypes()
+	conditions := []interface{}{}
+	for _, name := range statusTypes {
+		at, _ := lookupActionType(name)
+		then := structSchema(at.schema)
+		properties := then["properties"].(map[string]interface{})
+		properties["type"] = map[string]interface{}{"const": name}
+		properties["transition"] = map[string]interface{}{"$ref": "#/definitions/transition"}
+		properties["critical"] = map[string]interface{}{"type": "boolean"}
+		conditions = append(conditions, map[string]interface{}{
+			"if": map[string]interface{}{
+				"required": []interface{}{"type"},
+				"properties": map[string]interface{}{
+					"type": map[string]interface{}{"const": name},
+				},
+			},
+			"then": then,
+		})
+	}
+	types := make([]interface{}, len(statusTypes))
+	for i, name := range statusTypes {
+		types[i] = name
+	}
+
+	return map[string]interface{}{
+		"$schema":              "http://json-schema.org/draft-07/schema#",
+		"title":                "auroraops configuration",
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties": map[string]interface{}{
+			"panel": object(map[string]interface{}{
+				"url": map[string]interface{}{"type": "string"},
+				"key": map[string]interface{}{"type": "string"},
+			}),
+			"devices": map[string]interface{}{
+				"type":  "array",
+				"items": structSchema(reflect.TypeOf(DeviceConfig{})),
+			},
+			"status": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"location": map[string]interface{}{"type": "string"},
+					"interval": map[string]interface{}{"type": "integer", "minimum": 1},
+				},
+				"additionalProperties": map[string]interface{}{"$ref": "#/definitions/status"},
+			},
+			"things": map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": structSchema(reflect.TypeOf(ThingConfigSet{})),
+			},
+			"regions": map[string]interface{}{
+				"type": "object",
+				"additionalProperties": map[string]interface{}{
+					"type":  "array",
+					"items": structSchema(reflect.TypeOf(PanelSelector{})),
+				},
+			},
+			"schedules": map[string]interface{}{
+				"type":  "array",
+				"items": structSchema(reflect.TypeOf(ScheduleConfig{})),
+			},
+			"onstart": map[string]interface{}{"type": "string", "pattern": colorPattern},
+			"onstop":  map[string]interface{}{"type": "string", "pattern": colorPattern},
+			"validate": object(map[string]interface{}{
+				"thing":  map[string]interface{}{"type": "boolean"},
+				"status": map[string]interface{}{"type": "boolean"},
+			}),
+			"compositor": object(map[string]interface{}{
+				"fps":        map[string]interface{}{"type": "integer", "minimum": 1},
+				"brightness": map[string]interface{}{"type": "number", "minimum": 0, "maximum": 1},
+				"gamma":      map[string]interface{}{"type": "number", "minimum": 0},
+			}),
+			"control": object(map[string]interface{}{
+				"address": map[string]interface{}{"type": "string"},
+			}),
+			"layout": object(map[string]interface{}{
+				"snapshot": map[string]interface{}{"type": "string"},
+				"interval": map[string]interface{}{"type": "integer", "minimum": 1},
+				"remap":    map[string]interface{}{"type": "boolean"},
+			}),
+		},
+		"definitions": map[string]interface{}{
+			"transition": structSchema(reflect.TypeOf(TransitionConfig{})),
+			"status": map[string]interface{}{
+				"type":     "object",
+				"required": []interface{}{"type"},
+				"properties": map[string]interface{}{
+					"type": map[string]interface{}{"enum": types},
+				},
+				"allOf": conditions,
+			},
+		},
+	}
+}
+
+func object(properties map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+// structSchema describes a configuration struct by its mapstructure tags.
+// Fields tagged with schema:"color" hold hex colors, and fields tagged with
+// schema:"panel" hold panel IDs or references to panels of named devices.
+func structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("mapstructure"), ",")[0]
+		if name == "-" || field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		properties[name] = typeSchema(field.Type, field.Tag.Get("schema"))
+	}
+	return object(properties)
+}
+
+func typeSchema(t reflect.Type, format string) map[string]interface{} {
+	if t == durationType {
+		return map[string]interface{}{"type": []interface{}{"string", "integer"}, "pattern": durationPattern}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		if format == "color" {
+			return map[string]interface{}{"type": "string", "pattern": colorPattern}
+		}
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if format == "panel" {
+			return map[string]interface{}{"type": []interface{}{"integer", "string"}, "pattern": panelPattern}
+		}
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem(), format)}
+	case reflect.Map:
+		s := map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem(), format)}
+		if k := t.Key().Kind(); k >= reflect.Int && k <= reflect.Uint64 {
+			s["propertyNames"] = map[string]interface{}{"pattern": panelPattern}
+		}
+		return s
+	case reflect.Struct:
+		return structSchema(t)
+	case reflect.Ptr:
+		return typeSchema(t.Elem(), format)
+	}
+	return map[string]interface{}{}
+}
+
+// CheckSchema checks loaded configuration against ConfigSchema.
+func CheckSchema(config interface{}) []Diagnostic {
+	root := ConfigSchema()
+	c := &schemaChecker{definitions: root["definitions"].(map[string]interface{})}
+	c.check(root, config, []string{})
+	sort.SliceStable(c.diagnostics, func(i, j int) bool {
+		return strings.Join(c.diagnostics[i].Path, ".") < strings.Join(c.diagnostics[j].Path, ".")
+	})
+	return c.diagnostics
+}
+
+// schemaChecker validates values against the parts of JSON Schema that
+// ConfigSchema uses.
+type schemaChecker struct {
+	definitions map[string]interface{}
+	configChecker
+}
+
+func (c *schemaChecker) resolve(schema map[string]interface{}) map[string]interface{} {
+	if ref, ok := schema["$ref"].(string); ok {
+		return c.definitions[strings.TrimPrefix(ref, "#/definitions/")].(map[string]interface{})
+	}
+	return schema
+}
+
+// matches returns true when the value is valid without recording anything.
+func (c *schemaChecker) matches(schema map[string]interface{}, value interface{}) bool {
+	probe := &schemaChecker{definitions: c.definitions}
+	probe.check(schema, value, []string{})
+	return len(probe.diagnostics) == 0
+}
+
+func (c *schemaChecker) check(schema map[string]interface{}, value interface{}, path []string) {
+	schema = c.resolve(schema)
+
+	if types, ok := schema["type"]; ok && !matchesType(types, value) {
+		c.add(SeverityError, path, fmt.Sprintf("expected %s", describeTypes(types)))
+		return
+	}
+	if constant, ok := schema["const"]; ok && fmt.Sprint(constant) != fmt.Sprint(value) {
+		c.add(SeverityError, path, fmt.Sprintf("expected %v", constant))
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, option := range enum {
+			found = found || fmt.Sprint(option) == fmt.Sprint(value)
+		}
+		if !found {
+			c.add(SeverityError, path, fmt.Sprintf("unsupported value: %v", value))
+		}
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		if s, isString := value.(string); isString && !regexp.MustCompile(pattern).MatchString(s) {
+			c.add(SeverityError, path, fmt.Sprintf("invalid value: %s", s))
+		}
+	}
+	if minimum, ok := schema["minimum"]; ok {
+		if n, isNumber := toFloat(value); isNumber && n < toFloatOr(minimum) {
+			c.add(SeverityError, path, fmt.Sprintf("must be at least %v", minimum))
+		}
+	}
+	if maximum, ok := schema["maximum"]; ok {
+		if n, isNumber := toFloat(value); isNumber && n > toFloatOr(maximum) {
+			c.add(SeverityError, path, fmt.Sprintf("must be at most %v", maximum))
+		}
+	}
+
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		if list, isList := value.([]interface{}); isList {
+			for i, item := range list {
+				c.check(items, item, append(append([]string{}, path...), strconv.Itoa(i)))
+			}
+		}
+	}
+
+	if entries, isObject := toObject(value); isObject {
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, key := range required {
+				if _, present := entries[key.(string)]; !present {
+					c.add(SeverityError, path, fmt.Sprintf("missing %s", key))
+				}
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		names, _ := schema["propertyNames"].(map[string]interface{})
+		keys := make([]string, 0, len(entries))
+		for key := range entries {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			keyPath := append(append([]string{}, path...), key)
+			if names != nil {
+				c.check(names, key, keyPath)
+			}
+			if property, ok := properties[key]; ok {
+				c.check(property.(map[string]interface{}), entries[key], keyPath)
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					c.add(SeverityError, keyPath, "unknown key")
+				}
+			case map[string]interface{}:
+				c.check(additional, entries[key], keyPath)
+			}
+		}
+	}
+
+	if conditions, ok := schema["allOf"].([]interface{}); ok {
+		for _, condition := range conditions {
+			condition := condition.(map[string]interface{})
+			if when, ok := condition["if"].(map[string]interface{}); ok && !c.matches(when, value) {
+				continue
+			}
+			if then, ok := condition["then"].(map[string]interface{}); ok {
+				c.check(then, value, path)
+			}
+		}
+	}
+}
+
+// toObject returns the entries of a map decoded from either YAML or JSON.
+func toObject(value interface{}) (map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v, true
+	case map[interface{}]interface{}:
+		entries := make(map[string]interface{}, len(v))
+		for key, entry := range v {
+			entries[configKey(key)] = entry
+		}
+		return entries, true
+	}
+	return nil, false
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func toFloatOr(value interface{}) float64 {
+	n, _ := toFloat(value)
+	return n
+}
+
+func matchesType(types interface{}, value interface{}) bool {
+	options, ok := types.([]interface{})
+	if !ok {
+		options = []interface{}{types}
+	}
+	for _, option := range options {
+		switch option {
+		case "string":
+			if _, ok := value.(string); ok {
+				return true
+			}
+		case "boolean":
+			if _, ok := value.(bool); ok {
+				return true
+			}
+		case "integer":
+			if n, ok := toFloat(value); ok && n == float64(int64(n)) {
+				return true
+			}
+		case "number":
+			if _, ok := toFloat(value); ok {
+				return true
+			}
+		case "array":
+			if _, ok := value.([]interface{}); ok {
+				return true
+			}
+		case "object":
+			if _, ok := toObject(value); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func describeTypes(types interface{}) string {
+	if options, ok := types.([]interface{}); ok {
+		names := make([]string, len(options))
+		for i, option := range options {
+			names[i] = fmt.Sprint(option)
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(types)
+}
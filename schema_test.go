@@ -0,0 +1,132 @@
+package auroraops
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestCheckSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		want   []string
+	}{
+		{
+			name: "accepts a valid configuration",
+			config: map[string]interface{}{
+				"panel": map[string]interface{}{"url": "http://aurora", "key": "key"},
+				"status": map[string]interface{}{
+					"location": "http://status",
+					"interval": 30,
+					"up": map[string]interface{}{
+						"type":       "solid",
+						"color":      "#00ff00",
+						"transition": map[string]interface{}{"type": "crossfade", "duration": "1s"},
+					},
+				},
+				"things":     map[string]interface{}{"website": map[string]interface{}{"panels": []interface{}{1, 2}}},
+				"compositor": map[string]interface{}{"brightness": 0.5},
+			},
+			want: []string{},
+		},
+		{
+			name: "accepts maps decoded from yaml",
+			config: map[string]interface{}{
+				"things": map[interface{}]interface{}{"website": map[interface{}]interface{}{"panels": []interface{}{1}}},
+			},
+			want: []string{},
+		},
+		{
+			name:   "reports unknown keys",
+			config: map[string]interface{}{"colour": "#ffffff"},
+			want:   []string{"error: colour: unknown key"},
+		},
+		{
+			name: "reports statuses without a type",
+			config: map[string]interface{}{
+				"status": map[string]interface{}{"up": map[string]interface{}{"color": "#00ff00"}},
+			},
+			want: []string{"error: status.up: missing type"},
+		},
+		{
+			name: "reports unsupported status types",
+			config: map[string]interface{}{
+				"status": map[string]interface{}{"up": map[string]interface{}{"type": "sparkle"}},
+			},
+			want: []string{"error: status.up.type: unsupported value: sparkle"},
+		},
+		{
+			name: "checks statuses against their type",
+			config: map[string]interface{}{
+				"status": map[string]interface{}{"up": map[string]interface{}{"type": "solid", "color": "green", "speed": 2}},
+			},
+			want: []string{
+				"error: status.up.color: invalid value: green",
+				"error: status.up.speed: unknown key",
+			},
+		},
+		{
+			name: "reports values of the wrong type",
+			config: map[string]interface{}{
+				"things": map[string]interface{}{"website": map[string]interface{}{"panels": "1, 2", "priority": 1.5}},
+			},
+			want: []string{
+				"error: things.website.panels: expected array",
+				"error: things.website.priority: expected integer",
+			},
+		},
+		{
+			name: "reports values out of range",
+			config: map[string]interface{}{
+				"compositor": map[string]interface{}{"brightness": 2, "fps": 0},
+			},
+			want: []string{
+				"error: compositor.brightness: must be at most 1",
+				"error: compositor.fps: must be at least 1",
+			},
+		},
+		{
+			name: "reports invalid durations",
+			config: map[string]interface{}{
+				"status": map[string]interface{}{"up": map[string]interface{}{
+					"type":       "solid",
+					"transition": map[string]interface{}{"duration": "soon"},
+				}},
+			},
+			want: []string{"error: status.up.transition.duration: invalid value: soon"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, diagnostic := range CheckSchema(tt.config) {
+				got = append(got, diagnostic.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckSchema() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigSchema(t *testing.T) {
+	data, err := json.Marshal(ConfigSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := map[string]interface{}{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+	types := schema["definitions"].(map[string]interface{})["status"].(map[string]interface{})["properties"].(map[string]interface{})["type"].(map[string]interface{})["enum"].([]interface{})
+	for _, name := range ActionTypes() {
+		found := false
+		for _, option := range types {
+			found = found || option == name
+		}
+		if !found {
+			t.Errorf("status type %s is not in the schema", name)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package auroraops
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	colorful "github.com/lucasb-eyer/go-colorful"
+	"github.com/ngerakines/auroraops/client"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	starlarkmath "go.starlark.net/lib/math"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	tomb "gopkg.in/tomb.v2"
+)
+
+// scriptSteps caps how much work a script may do in one call, on top of the
+// time limit.
+const scriptSteps = 1000000
+
+type scriptAction struct {
+	name    string
+	panels  []int
+	render  starlark.Callable
+	geo     *starlark.List
+	status  string
+	value   float64
+	timeout time.Duration
+	still   bool
+
+	canvas Canvas
+
+	t  tomb.Tomb
+	mu sync.Mutex
+}
+
+// NewScriptAction creates an action from a Starlark script. The script must
+// define a render(panels, elapsed, status, value) function that is called for
+// every frame with the panels (each with an id, x, y and rotation), the
+// seconds since the action started, the status and its numeric value. It
+// returns a list with a color for each panel, either a hex string or an
+// (r, g, b) tuple from 0 to 255. Scripts cannot reach the filesystem or
+// network, and every call must finish within the timeout.
+func NewScriptAction(canvas Canvas, panels []*client.Panel, name string, source []byte, status string, value float64, timeout time.Duration) (Action, error) {
+	log.WithField("script", name).Info("New script action")
+	if timeout <= 0 {
+		return nil, fmt.Errorf("error: timeout must be greater than zero")
+	}
+	a := &scriptAction{
+		name:    name,
+		status:  status,
+		value:   value,
+		timeout: timeout,
+		canvas:  canvas,
+	}
+
+	geo := []starlark.Value{}
+	for _, panel := range panels {
+		a.panels = append(a.panels, panel.ID)
+		geo = append(geo, starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+			"id":       starlark.MakeInt(panel.ID),
+			"x":        starlark.MakeInt(panel.X),
+			"y":        starlark.MakeInt(panel.Y),
+			"rotation": starlark.MakeInt(panel.Rotation),
+		}))
+	}
+	a.geo = starlark.NewList(geo)
+	a.geo.Freeze()
+
+	predeclared := starlark.StringDict{
+		"math": starlarkmath.Module,
+	}
+	thread, done := a.thread()
+	globals, err := starlark.ExecFile(thread, name, source, predeclared)
+	done()
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not load script %s", name)
+	}
+	render, ok := globals["render"].(starlark.Callable)
+	if !ok {
+		return nil, fmt.Errorf("error: script %s does not define a render function", name)
+	}
+	a.render = render
+	return a, nil
+}
+
+// thread creates an interpreter thread that is cancelled when it runs for
+// longer than the timeout. The returned function must be called once the
+// thread is no longer used.
+func (a *scriptAction) thread() (*starlark.Thread, func()) {
+	thread := &starlark.Thread{
+		Name: a.name,
+		Print: func(_ *starlark.Thread, msg string) {
+			log.WithField("script", a.name).Info(msg)
+		},
+		Load: func(_ *starlark.Thread, module string) (starlark.StringDict, error) {
+			return nil, fmt.Errorf("error: scripts cannot load modules")
+		},
+	}
+	thread.SetMaxExecutionSteps(scriptSteps)
+	timer := time.AfterFunc(a.timeout, func() {
+		thread.Cancel("timed out")
+	})
+	return thread, func() {
+		timer.Stop()
+	}
+}
+
+func (a *scriptAction) draw(elapsed time.Duration) error {
+	thread, done := a.thread()
+	args := starlark.Tuple{a.geo, starlark.Float(elapsed.Seconds()), starlark.String(a.status), starlark.Float(a.value)}
+	result, err := starlark.Call(thread, a.render, args, nil)
+	done()
+	if err != nil {
+		return err
+	}
+	colors, ok := result.(starlark.Indexable)
+	if !ok {
+		return fmt.Errorf("error: render returned %s, not a list", result.Type())
+	}
+	if colors.Len() != len(a.panels) {
+		return fmt.Errorf("error: render returned %d colors for %d panels", colors.Len(), len(a.panels))
+	}
+	for i, panel := range a.panels {
+		color, err := scriptColor(colors.Index(i))
+		if err != nil {
+			return err
+		}
+		a.canvas.SetPanelColor(panel, color)
+	}
+	return nil
+}
+
+// scriptColor converts a hex string or an (r, g, b) tuple returned by a script
+// into a color.
+func scriptColor(v starlark.Value) (colorful.Color, error) {
+	if hex, ok := v.(starlark.String); ok {
+		return colorful.Hex(string(hex))
+	}
+	tuple, ok := v.(starlark.Tuple)
+	if !ok || len(tuple) != 3 {
+		return colorful.Color{}, fmt.Errorf("error: %s is not a color", v)
+	}
+	rgb := [3]float64{}
+	for i, channel := range tuple {
+		f, ok := starlark.AsFloat(channel)
+		if !ok {
+			return colorful.Color{}, fmt.Errorf("error: %s is not a color", v)
+		}
+		rgb[i] = clamp(f / 255)
+	}
+	return colorful.Color{R: rgb[0], G: rgb[1], B: rgb[2]}, nil
+}
+
+func (a *scriptAction) loop() error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	start := time.Now()
+	for {
+		select {
+		case t := <-ticker.C:
+			a.mu.Lock()
+			err := a.draw(t.Sub(start))
+			a.mu.Unlock()
+			if err != nil {
+				log.WithError(err).WithField("script", a.name).Error("Could not render script.")
+			}
+		case <-a.t.Dying():
+			return nil
+		}
+	}
+}
+
+func (a *scriptAction) Start() error {
+	if a.still {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		return a.draw(0)
+	}
+	a.t.Go(a.loop)
+	return nil
+}
+
+func (a *scriptAction) SetValue(value float64) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.value = value
+	return nil
+}
+
+func (a *scriptAction) Stop(ctx context.Context) error {
+	log.WithField("action", "script").Info("Stopping")
+	if a.still {
+		return nil
+	}
+	a.t.Kill(nil)
+	return a.t.Wait()
+}
+
+// scriptConfig limits each frame to 20ms by default. Script is relative to the
+// configuration file and must be inside its directory. Still draws only the
+// first frame.
+type scriptConfig struct {
+	Script  string        `mapstructure:"script"`
+	Timeout time.Duration `mapstructure:"timeout"`
+	Still   bool          `mapstructure:"still"`
+}
+
+func init() {
+	RegisterAction("script", newScriptFromConfig, scriptConfig{})
+}
+
+func newScriptFromConfig(config map[string]interface{}, ac ActionContext) (Action, error) {
+	sc := scriptConfig{}
+	if err := ac.DecodeConfig(config, &sc); err != nil {
+		return nil, err
+	}
+	script, err := scriptPath(sc.Script)
+	if err != nil {
+		return nil, err
+	}
+	source, err := ioutil.ReadFile(script)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read script: %s", sc.Script)
+	}
+	timeout := sc.Timeout
+	if timeout <= 0 {
+		timeout = 20 * time.Millisecond
+	}
+	action, err := NewScriptAction(ac.Canvas, ac.Geometry, sc.Script, source, ac.Status, ac.Value, timeout)
+	if err != nil {
+		return nil, err
+	}
+	action.(*scriptAction).still = sc.Still
+	return action, nil
+}
+
+// scriptPath resolves a script against the directory of the configuration
+// file, and rejects scripts outside of it.
+func scriptPath(script string) (string, error) {
+	if filepath.IsAbs(script) {
+		return "", fmt.Errorf("error: script must be relative to the configuration file: %s", script)
+	}
+	script = filepath.Clean(script)
+	if script == ".." || strings.HasPrefix(script, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("error: script must be inside the directory of the configuration file: %s", script)
+	}
+	if file := viper.ConfigFileUsed(); file != "" {
+		script = filepath.Join(filepath.Dir(file), script)
+	}
+	return script, nil
+}
@@ -0,0 +1,195 @@
+package auroraops
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ngerakines/auroraops/client"
+	"github.com/spf13/viper"
+)
+
+func TestScriptPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		want    string
+		wantErr bool
+	}{
+		{name: "accepts scripts next to the configuration", script: "wave.star", want: "wave.star"},
+		{name: "accepts scripts in subdirectories", script: "scripts/../wave.star", want: "wave.star"},
+		{name: "rejects absolute paths", script: "/etc/passwd", wantErr: true},
+		{name: "rejects paths that leave the directory", script: "scripts/../../wave.star", wantErr: true},
+		{name: "rejects the parent directory", script: "..", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := scriptPath(tt.script)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("scriptPath(%q) error = %v, wantErr %v", tt.script, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != filepath.FromSlash(tt.want) {
+				t.Errorf("scriptPath(%q) = %q, want %q", tt.script, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStillScript(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auroraops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	source := "def render(panels, elapsed, status, value):\n    return [(255 * elapsed, 0, 255) for p in panels]\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "still.star"), []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	viper.SetConfigFile(filepath.Join(dir, "auroraops.yaml"))
+	defer viper.Reset()
+
+	canvas := recordingCanvas{}
+	action, err := newAction(stillStatus(StatusConfigSet{Type: "script", Config: map[string]interface{}{"script": "still.star"}}), ActionContext{
+		Geometry: []*client.Panel{{ID: 1}, {ID: 2}},
+		Canvas:   canvas,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := action.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := action.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := canvas.hexes(), map[int]string{1: "#0000ff", 2: "#0000ff"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("colors = %v, want %v", got, want)
+	}
+}
+
+func TestScriptAction(t *testing.T) {
+	panels := []*client.Panel{{ID: 1, X: 0}, {ID: 2, X: 100}}
+	tests := []struct {
+		name    string
+		source  string
+		status  string
+		value   float64
+		elapsed time.Duration
+		want    map[int]string
+	}{
+		{
+			name:   "draws hex strings",
+			source: "def render(panels, elapsed, status, value):\n    return ['#ff0000', '#00ff00']\n",
+			want:   map[int]string{1: "#ff0000", 2: "#00ff00"},
+		},
+		{
+			name:   "draws and clamps tuples",
+			source: "def render(panels, elapsed, status, value):\n    return [(255, 0, 0), (-10, 128, 300)]\n",
+			want:   map[int]string{1: "#ff0000", 2: "#0080ff"},
+		},
+		{
+			name:   "is given the panels",
+			source: "def render(panels, elapsed, status, value):\n    return [(p.x * 2, p.id, 0) for p in panels]\n",
+			want:   map[int]string{1: "#000100", 2: "#c80200"},
+		},
+		{
+			name:    "is given the elapsed seconds",
+			source:  "def render(panels, elapsed, status, value):\n    return [(0, 0, int(elapsed * 100))] * len(panels)\n",
+			elapsed: 1500 * time.Millisecond,
+			want:    map[int]string{1: "#000096", 2: "#000096"},
+		},
+		{
+			name:   "is given the status and value",
+			source: "def render(panels, elapsed, status, value):\n    c = (value, 0, 0) if status == 'down' else (0, value, 0)\n    return [c, c]\n",
+			status: "down",
+			value:  64,
+			want:   map[int]string{1: "#400000", 2: "#400000"},
+		},
+		{
+			name:   "can use math",
+			source: "def render(panels, elapsed, status, value):\n    return [(math.floor(math.sqrt(256)), 0, 0)] * 2\n",
+			want:   map[int]string{1: "#100000", 2: "#100000"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			canvas := recordingCanvas{}
+			action, err := NewScriptAction(canvas, panels, "test.star", []byte(tt.source), tt.status, tt.value, time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := action.(*scriptAction).draw(tt.elapsed); err != nil {
+				t.Fatal(err)
+			}
+			if got := canvas.hexes(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("colors = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScriptErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		wantErr string
+	}{
+		{
+			name:    "syntax errors",
+			source:  "def render(panels, elapsed, status, value)\n",
+			wantErr: "could not load script test.star",
+		},
+		{
+			name:    "scripts without render",
+			source:  "def draw(panels, elapsed, status, value):\n    return []\n",
+			wantErr: "script test.star does not define a render function",
+		},
+		{
+			name:    "loading modules",
+			source:  "load('os.star', 'os')\n",
+			wantErr: "scripts cannot load modules",
+		},
+		{
+			name:    "endless work",
+			source:  "def render(panels, elapsed, status, value):\n    for i in range(100000000):\n        pass\n    return []\n",
+			wantErr: "too many steps",
+		},
+		{
+			name:    "results that are not a list",
+			source:  "def render(panels, elapsed, status, value):\n    return 7\n",
+			wantErr: "render returned int, not a list",
+		},
+		{
+			name:    "a color for every panel",
+			source:  "def render(panels, elapsed, status, value):\n    return ['#ffffff']\n",
+			wantErr: "render returned 1 colors for 2 panels",
+		},
+		{
+			name:    "invalid colors",
+			source:  "def render(panels, elapsed, status, value):\n    return [(1, 2), '#ffffff']\n",
+			wantErr: "(1, 2) is not a color",
+		},
+		{
+			name:    "errors raised by the script",
+			source:  "def render(panels, elapsed, status, value):\n    fail('broken')\n",
+			wantErr: "broken",
+		},
+	}
+	panels := []*client.Panel{{ID: 1}, {ID: 2}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, err := NewScriptAction(recordingCanvas{}, panels, "test.star", []byte(tt.source), "", 0, time.Second)
+			if err == nil {
+				err = action.(*scriptAction).draw(0)
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package auroraops
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/ngerakines/auroraops/client"
+	"github.com/pkg/errors"
+)
+
+// PanelSelector selects panels by where they are on the wall rather than by
+// ID, so that things keep their place when panels are remounted or replaced.
+// Every criteria that is set must match, and a thing or region with several
+// selectors gets the panels of each.
+//
+// Left, Right, Top and Bottom select the panels in that fraction of the wall,
+// so Left 0.33 is the left third. Box selects the panels inside the layout
+// coordinates [x1, y1, x2, y2]. Row and Column count from 1 at the top left.
+// Nearest selects the Count panels closest to the point [x, y], one by default.
+// Region stands for the selectors of a named region, and cannot be combined
+// with other criteria.
+type PanelSelector struct {
+	Region  string    `mapstructure:"region"`
+	Left    float64   `mapstructure:"left"`
+	Right   float64   `mapstructure:"right"`
+	Top     float64   `mapstructure:"top"`
+	Bottom  float64   `mapstructure:"bottom"`
+	Box     []float64 `mapstructure:"box"`
+	Row     int       `mapstructure:"row"`
+	Column  int       `mapstructure:"column"`
+	Nearest []float64 `mapstructure:"nearest"`
+	Count   int       `mapstructure:"count"`
+}
+
+// ParseRegionConfig reads the regions section of the configuration, which
+// names lists of selectors. Region names are not case sensitive, like the rest
+// of the configuration, and are returned lowercased.
+func ParseRegionConfig(raw interface{}) (map[string][]PanelSelector, error) {
+	decoded := make(map[string][]PanelSelector)
+	if err := decodeConfig(raw, &decoded, true); err != nil {
+		return nil, err
+	}
+	regions := make(map[string][]PanelSelector, len(decoded))
+	for name, selectors := range decoded {
+		regions[strings.ToLower(name)] = selectors
+	}
+	return regions, nil
+}
+
+// ApplyRegions replaces the named regions that things select with the
+// selectors of those regions.
+func ApplyRegions(things map[string]ThingConfigSet, regions map[string][]PanelSelector) (map[string]ThingConfigSet, error) {
+	applied := make(map[string]ThingConfigSet, len(things))
+	for thing, thingConfig := range things {
+		selectors, err := expandRegions(thingConfig.Select, regions, []string{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "thing %s", thing)
+		}
+		thingConfig.Select = selectors
+		applied[thing] = thingConfig
+	}
+	return applied, nil
+}
+
+func expandRegions(selectors []PanelSelector, regions map[string][]PanelSelector, seen []string) ([]PanelSelector, error) {
+	if selectors == nil {
+		return nil, nil
+	}
+	expanded := []PanelSelector{}
+	for _, selector := range selectors {
+		if selector.Region == "" {
+			expanded = append(expanded, selector)
+			continue
+		}
+		if selector.hasCriteria() {
+			return nil, fmt.Errorf("error: region %s cannot be combined with other criteria", selector.Region)
+		}
+		name := strings.ToLower(selector.Region)
+		if containsString(seen, name) {
+			return nil, fmt.Errorf("error: region %s refers to itself", selector.Region)
+		}
+		region, ok := regions[name]
+		if !ok {
+			return nil, fmt.Errorf("error: unknown region: %s", selector.Region)
+		}
+		selectors, err := expandRegions(region, regions, append(seen, name))
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, selectors...)
+	}
+	return expanded, nil
+}
+
+// hasCriteria returns true when the selector selects by anything but region.
+func (selector PanelSelector) hasCriteria() bool {
+	return selector.Left != 0 || selector.Right != 0 || selector.Top != 0 || selector.Bottom != 0 ||
+		selector.Box != nil || selector.Row != 0 || selector.Column != 0 || selector.Nearest != nil || selector.Count != 0
+}
+
+func checkSelector(selector PanelSelector) error {
+	for _, fraction := range []float64{selector.Left, selector.Right, selector.Top, selector.Bottom} {
+		if fraction < 0 || fraction > 1 {
+			return fmt.Errorf("error: fraction %v must be between 0 and 1", fraction)
+		}
+	}
+	if selector.Box != nil && len(selector.Box) != 4 {
+		return fmt.Errorf("error: box must be [x1, y1, x2, y2]")
+	}
+	if selector.Row < 0 || selector.Column < 0 {
+		return fmt.Errorf("error: rows and columns count from 1")
+	}
+	if selector.Nearest != nil && len(selector.Nearest) != 2 {
+		return fmt.Errorf("error: nearest must be [x, y]")
+	}
+	if selector.Count < 0 || (selector.Count > 0 && selector.Nearest == nil) {
+		return fmt.Errorf("error: count must be positive and is only used with nearest")
+	}
+	return nil
+}
+
+// selectPanels returns the configured panels of a thing followed by the panels
+// its selectors select from the layout, without duplicates.
+func selectPanels(thingConfig ThingConfigSet, layout []*client.Panel) ([]int, error) {
+	panels := append([]int{}, thingConfig.Panels...)
+	if len(thingConfig.Select) == 0 {
+		return panels, nil
+	}
+	g := newWallGeometry(layout)
+	for _, selector := range thingConfig.Select {
+		if selector.Region != "" {
+			return nil, fmt.Errorf("error: unknown region: %s", selector.Region)
+		}
+		if err := checkSelector(selector); err != nil {
+			return nil, err
+		}
+		for _, panel := range g.match(selector) {
+			if !containsInt(panels, panel.ID) {
+				panels = append(panels, panel.ID)
+			}
+		}
+	}
+	return panels, nil
+}
+
+// wallGeometry is the extent of the wall and the rows and columns its panels
+// fall into.
+type wallGeometry struct {
+	panels                 []*client.Panel
+	minX, maxX, minY, maxY float64
+	rows, columns          map[int]int
+}
+
+func newWallGeometry(layout []*client.Panel) *wallGeometry {
+	g := &wallGeometry{
+		panels: layout,
+		minX:   math.Inf(1),
+		maxX:   math.Inf(-1),
+		minY:   math.Inf(1),
+		maxY:   math.Inf(-1),
+	}
+	sideLength := 0
+	for _, panel := range layout {
+		g.minX = math.Min(g.minX, float64(panel.X))
+		g.maxX = math.Max(g.maxX, float64(panel.X))
+		g.minY = math.Min(g.minY, float64(panel.Y))
+		g.maxY = math.Max(g.maxY, float64(panel.Y))
+		if panel.SideLength > sideLength {
+			sideLength = panel.SideLength
+		}
+	}
+	if sideLength == 0 {
+		sideLength = 150
+	}
+	// The centers of neighbouring triangles are offset by less than a third of
+	// their side, while neighbouring rows and columns are further apart.
+	tolerance := float64(sideLength) / 3
+	g.rows = group(layout, func(panel *client.Panel) float64 { return -float64(panel.Y) }, tolerance)
+	g.columns = group(layout, func(panel *client.Panel) float64 { return float64(panel.X) }, tolerance)
+	return g
+}
+
+// group numbers panels from 1 by a coordinate, starting a new group wherever
+// the gap to the previous panel is larger than the tolerance.
+func group(layout []*client.Panel, coordinate func(*client.Panel) float64, tolerance float64) map[int]int {
+	sorted := append([]*client.Panel{}, layout...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return coordinate(sorted[i]) < coordinate(sorted[j])
+	})
+	groups := make(map[int]int)
+	number := 0
+	for i, panel := range sorted {
+		if i == 0 || coordinate(panel)-coordinate(sorted[i-1]) > tolerance {
+			number = number + 1
+		}
+		groups[panel.ID] = number
+	}
+	return groups
+}
+
+func (g *wallGeometry) match(selector PanelSelector) []*client.Panel {
+	matched := []*client.Panel{}
+	width, height := g.maxX-g.minX, g.maxY-g.minY
+	for _, panel := range g.panels {
+		x, y := float64(panel.X), float64(panel.Y)
+		if selector.Left > 0 && x > g.minX+width*selector.Left {
+			continue
+		}
+		if selector.Right > 0 && x < g.maxX-width*selector.Right {
+			continue
+		}
+		if selector.Top > 0 && y < g.maxY-height*selector.Top {
+			continue
+		}
+		if selector.Bottom > 0 && y > g.minY+height*selector.Bottom {
+			continue
+		}
+		if len(selector.Box) == 4 {
+			b := selector.Box
+			if x < math.Min(b[0], b[2]) || x > math.Max(b[0], b[2]) || y < math.Min(b[1], b[3]) || y > math.Max(b[1], b[3]) {
+				continue
+			}
+		}
+		if selector.Row > 0 && g.rows[panel.ID] != selector.Row {
+			continue
+		}
+		if selector.Column > 0 && g.columns[panel.ID] != selector.Column {
+			continue
+		}
+		matched = append(matched, panel)
+	}
+
+	if len(selector.Nearest) == 2 {
+		point := selector.Nearest
+		distance := func(panel *client.Panel) float64 {
+			return math.Hypot(float64(panel.X)-point[0], float64(panel.Y)-point[1])
+		}
+		sort.SliceStable(matched, func(i, j int) bool {
+			return distance(matched[i]) < distance(matched[j])
+		})
+		count := selector.Count
+		if count == 0 {
+			count = 1
+		}
+		if count < len(matched) {
+			matched = matched[:count]
+		}
+	}
+	return matched
+}
@@ -0,0 +1,88 @@
+package auroraops
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ngerakines/auroraops/client"
+)
+
+// gridLayout is a wall of three rows of three panels, numbered from the top
+// left.
+var gridLayout = []*client.Panel{
+	{ID: 1, X: 0, Y: 300}, {ID: 2, X: 150, Y: 300}, {ID: 3, X: 300, Y: 300},
+	{ID: 4, X: 0, Y: 150}, {ID: 5, X: 150, Y: 150}, {ID: 6, X: 300, Y: 150},
+	{ID: 7, X: 0, Y: 0}, {ID: 8, X: 150, Y: 0}, {ID: 9, X: 300, Y: 0},
+}
+
+func TestSelectPanels(t *testing.T) {
+	tests := []struct {
+		name    string
+		panels  []int
+		selects []PanelSelector
+		want    []int
+		wantErr bool
+	}{
+		{name: "configured panels", panels: []int{5}, want: []int{5}},
+		{name: "left", selects: []PanelSelector{{Left: 0.33}}, want: []int{1, 4, 7}},
+		{name: "right", selects: []PanelSelector{{Right: 0.33}}, want: []int{3, 6, 9}},
+		{name: "top", selects: []PanelSelector{{Top: 0.33}}, want: []int{1, 2, 3}},
+		{name: "bottom", selects: []PanelSelector{{Bottom: 0.33}}, want: []int{7, 8, 9}},
+		{name: "every criteria must match", selects: []PanelSelector{{Left: 0.33, Top: 0.33}}, want: []int{1}},
+		{name: "box", selects: []PanelSelector{{Box: []float64{200, 200, 100, 100}}}, want: []int{5}},
+		{name: "row", selects: []PanelSelector{{Row: 2}}, want: []int{4, 5, 6}},
+		{name: "column", selects: []PanelSelector{{Column: 3}}, want: []int{3, 6, 9}},
+		{name: "nearest", selects: []PanelSelector{{Nearest: []float64{10, 10}}}, want: []int{7}},
+		{name: "nearest several", selects: []PanelSelector{{Nearest: []float64{0, 0}, Count: 3}}, want: []int{7, 4, 8}},
+		{name: "each selector", selects: []PanelSelector{{Row: 1}, {Column: 1}}, want: []int{1, 2, 3, 4, 7}},
+		{name: "configured panels first without duplicates", panels: []int{2}, selects: []PanelSelector{{Row: 1}}, want: []int{2, 1, 3}},
+		{name: "unexpanded region", selects: []PanelSelector{{Region: "top"}}, wantErr: true},
+		{name: "invalid selector", selects: []PanelSelector{{Left: 2}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectPanels(ThingConfigSet{Panels: tt.panels, Select: tt.selects}, gridLayout)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectPanels() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectPanels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandRegions(t *testing.T) {
+	regions := map[string][]PanelSelector{
+		"top":     {{Row: 1}},
+		"corners": {{Region: "Top"}, {Row: 3, Column: 1}},
+		"loop":    {{Region: "again"}},
+		"again":   {{Region: "LOOP"}},
+	}
+	tests := []struct {
+		name      string
+		selectors []PanelSelector
+		want      []PanelSelector
+		wantErr   bool
+	}{
+		{name: "no selectors", selectors: nil, want: nil},
+		{name: "selectors without regions", selectors: []PanelSelector{{Row: 2}}, want: []PanelSelector{{Row: 2}}},
+		{name: "region", selectors: []PanelSelector{{Region: "top"}}, want: []PanelSelector{{Row: 1}}},
+		{name: "region regardless of case", selectors: []PanelSelector{{Region: "Top"}}, want: []PanelSelector{{Row: 1}}},
+		{name: "nested regions", selectors: []PanelSelector{{Region: "corners"}}, want: []PanelSelector{{Row: 1}, {Row: 3, Column: 1}}},
+		{name: "unknown region", selectors: []PanelSelector{{Region: "middle"}}, wantErr: true},
+		{name: "region with other criteria", selectors: []PanelSelector{{Region: "top", Left: 0.5}}, wantErr: true},
+		{name: "region that refers to itself", selectors: []PanelSelector{{Region: "loop"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandRegions(tt.selectors, regions, []string{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expandRegions() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expandRegions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package auroraops
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	colorful "github.com/lucasb-eyer/go-colorful"
+	"github.com/ngerakines/auroraops/client"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	tomb "gopkg.in/tomb.v2"
+)
+
+// spatialAction animates panels by where they are on the wall. Every panel
+// has a field value between 0 and 1 derived from its position, and on every
+// tick the shade function turns that value and the phase of the animation
+// into how far the panel is blended from the background to the color.
+type spatialAction struct {
+	name   string
+	panels []int
+	fields []float64
+	shade  func(field, phase float64) float64
+	from   colorful.Color
+	to     colorful.Color
+	blend  Blend
+	period time.Duration
+
+	canvas Canvas
+
+	t  tomb.Tomb
+	mu sync.Mutex
+}
+
+func newSpatialAction(name string, canvas Canvas, panels []*client.Panel, fields []float64, shade func(field, phase float64) float64, from, to colorful.Color, period time.Duration, blend Blend) (Action, error) {
+	log.WithField("action", name).Info("New spatial action")
+	if !from.IsValid() || !to.IsValid() {
+		return nil, fmt.Errorf("error: invalid color")
+	}
+	if period <= 0 {
+		return nil, fmt.Errorf("error: period must be greater than zero")
+	}
+	ids := make([]int, len(panels))
+	for i, panel := range panels {
+		ids[i] = panel.ID
+	}
+	return &spatialAction{
+		name:   name,
+		panels: ids,
+		fields: fields,
+		shade:  shade,
+		from:   from,
+		to:     to,
+		blend:  blend,
+		period: period,
+		canvas: canvas,
+	}, nil
+}
+
+// NewWipeAction creates an action that fills the panels with a color in the
+// given direction, in degrees counter-clockwise from left to right, and then
+// starts over. Width is the fraction of the wall covered by the soft edge.
+func NewWipeAction(canvas Canvas, panels []*client.Panel, from, to colorful.Color, period time.Duration, direction, width float64, blend Blend) (Action, error) {
+	shade := func(field, phase float64) float64 {
+		return clamp((phase*(1+width) - field) / width)
+	}
+	return newSpatialAction("wipe", canvas, panels, projections(panels, direction), shade, from, to, period, blend)
+}
+
+// NewRippleAction creates an action that sends rings of color outwards from a
+// center panel. When center is nil, rings start from the middle of the
+// panels. Width is the fraction of the distance covered by a ring.
+func NewRippleAction(canvas Canvas, panels []*client.Panel, center *client.Panel, from, to colorful.Color, period time.Duration, width float64, blend Blend) (Action, error) {
+	x, y := centroid(panels)
+	if center != nil {
+		x, y = float64(center.X), float64(center.Y)
+	}
+	shade := func(field, phase float64) float64 {
+		ring := phase*(1+width) - width/2
+		return clamp(1 - math.Abs(field-ring)/(width/2))
+	}
+	return newSpatialAction("ripple", canvas, panels, distances(panels, x, y), shade, from, to, period, blend)
+}
+
+// NewSweepAction creates an action that rotates a beam of color around the
+// middle of the panels, counter-clockwise. Width is the fraction of a full turn
+// covered by the beam.
+func NewSweepAction(canvas Canvas, panels []*client.Panel, from, to colorful.Color, period time.Duration, width float64, blend Blend) (Action, error) {
+	x, y := centroid(panels)
+	shade := func(field, phase float64) float64 {
+		d := math.Abs(field - phase)
+		d = math.Min(d, 1-d)
+		return clamp(1 - d/(width/2))
+	}
+	return newSpatialAction("sweep", canvas, panels, angles(panels, x, y), shade, from, to, period, blend)
+}
+
+func (a *spatialAction) draw(elapsed time.Duration) {
+	phase := float64(elapsed%a.period) / float64(a.period)
+	for i, panel := range a.panels {
+		a.canvas.SetPanelColor(panel, a.blend(a.from, a.to, a.shade(a.fields[i], phase)))
+	}
+}
+
+func (a *spatialAction) loop() error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	start := time.Now()
+	a.draw(0)
+	for {
+		select {
+		case t := <-ticker.C:
+			a.draw(t.Sub(start))
+		case <-a.t.Dying():
+			return nil
+		}
+	}
+}
+
+func (a *spatialAction) Start() error {
+	a.t.Go(a.loop)
+	return nil
+}
+
+func (a *spatialAction) Stop(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	log.WithField("action", a.name).Info("Stopping")
+	a.t.Kill(nil)
+	return a.t.Wait()
+}
+
+// spatialConfig is shared by wipe, ripple and sweep actions, which animate
+// from black over two seconds by default.
+type spatialConfig struct {
+	Color     string        `mapstructure:"color" schema:"color"`
+	From      string        `mapstructure:"from" schema:"color"`
+	Period    time.Duration `mapstructure:"period"`
+	Width     float64       `mapstructure:"width"`
+	Blend     string        `mapstructure:"blend"`
+	Direction float64       `mapstructure:"direction"`
+	Center    *int          `mapstructure:"center" schema:"panel"`
+}
+
+func init() {
+	RegisterAction("wipe", newSpatialFromConfig("wipe"), spatialConfig{})
+	RegisterAction("ripple", newSpatialFromConfig("ripple"), spatialConfig{})
+	RegisterAction("sweep", newSpatialFromConfig("sweep"), spatialConfig{})
+}
+
+func newSpatialFromConfig(name string) ActionFactory {
+	return func(config map[string]interface{}, ac ActionContext) (Action, error) {
+		sc := spatialConfig{}
+		if err := ac.DecodeConfig(config, &sc); err != nil {
+			return nil, err
+		}
+		to, err := colorful.Hex(sc.Color)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid color: %s", sc.Color)
+		}
+		from, err := colorOrDefault(sc.From, colorful.Color{})
+		if err != nil {
+			return nil, err
+		}
+		period := sc.Period
+		if period <= 0 {
+			period = 2 * time.Second
+		}
+		width := sc.Width
+		if width <= 0 {
+			width = 0.3
+		}
+		blend, err := BlendFor(sc.Blend)
+		if err != nil {
+			return nil, err
+		}
+		switch name {
+		case "wipe":
+			return NewWipeAction(ac.Canvas, ac.Geometry, from, to, period, sc.Direction, width, blend)
+		case "ripple":
+			var center *client.Panel
+			if sc.Center != nil {
+				var ok bool
+				if center, ok = ac.Layout[*sc.Center]; !ok {
+					return nil, fmt.Errorf("unknown center panel: %d", *sc.Center)
+				}
+			}
+			return NewRippleAction(ac.Canvas, ac.Geometry, center, from, to, period, width, blend)
+		}
+		return NewSweepAction(ac.Canvas, ac.Geometry, from, to, period, width, blend)
+	}
+}
@@ -0,0 +1,128 @@
+package auroraops
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ngerakines/auroraops/client"
+)
+
+func TestSpatialActions(t *testing.T) {
+	row := []*client.Panel{{ID: 1, X: 0}, {ID: 2, X: 100}, {ID: 3, X: 200}}
+	cross := []*client.Panel{{ID: 1, X: 100}, {ID: 2, Y: 100}, {ID: 3, X: -100}, {ID: 4, Y: -100}}
+	tests := []struct {
+		name       string
+		actionType string
+		config     map[string]interface{}
+		panels     []*client.Panel
+		elapsed    time.Duration
+		want       map[int]string
+	}{
+		{
+			name:       "wipe fills from the left",
+			actionType: "wipe",
+			config:     map[string]interface{}{"color": "#ffffff", "period": "1s", "width": 0.5, "blend": "rgb"},
+			panels:     row,
+			elapsed:    500 * time.Millisecond,
+			want:       map[int]string{1: "#ffffff", 2: "#808080", 3: "#000000"},
+		},
+		{
+			name:       "wipe fills in its direction",
+			actionType: "wipe",
+			config:     map[string]interface{}{"color": "#ffffff", "period": "1s", "width": 0.5, "blend": "rgb", "direction": 180},
+			panels:     row,
+			elapsed:    500 * time.Millisecond,
+			want:       map[int]string{1: "#000000", 2: "#808080", 3: "#ffffff"},
+		},
+		{
+			name:       "wipe starts over every period",
+			actionType: "wipe",
+			config:     map[string]interface{}{"color": "#ffffff", "from": "#0000ff", "period": "1s", "width": 0.5, "blend": "rgb"},
+			panels:     row,
+			elapsed:    time.Second,
+			want:       map[int]string{1: "#0000ff", 2: "#0000ff", 3: "#0000ff"},
+		},
+		{
+			name:       "ripple starts at the middle of the panels",
+			actionType: "ripple",
+			config:     map[string]interface{}{"color": "#ffffff", "period": "1s", "width": 0.5, "blend": "rgb"},
+			panels:     row,
+			elapsed:    250 * time.Millisecond,
+			want:       map[int]string{1: "#000000", 2: "#808080", 3: "#000000"},
+		},
+		{
+			name:       "ripple starts at its center panel",
+			actionType: "ripple",
+			config:     map[string]interface{}{"color": "#ffffff", "period": "1s", "width": 0.5, "blend": "rgb", "center": 1},
+			panels:     row,
+			elapsed:    250 * time.Millisecond,
+			want:       map[int]string{1: "#808080", 2: "#000000", 3: "#000000"},
+		},
+		{
+			name:       "ripple moves outwards",
+			actionType: "ripple",
+			config:     map[string]interface{}{"color": "#ffffff", "period": "1s", "width": 0.5, "blend": "rgb", "center": 1},
+			panels:     row,
+			elapsed:    500 * time.Millisecond,
+			want:       map[int]string{1: "#000000", 2: "#ffffff", 3: "#000000"},
+		},
+		{
+			name:       "sweep starts to the right",
+			actionType: "sweep",
+			config:     map[string]interface{}{"color": "#ffffff", "period": "1s", "width": 0.5, "blend": "rgb"},
+			panels:     cross,
+			elapsed:    0,
+			want:       map[int]string{1: "#ffffff", 2: "#000000", 3: "#000000", 4: "#000000"},
+		},
+		{
+			name:       "sweep turns counter-clockwise",
+			actionType: "sweep",
+			config:     map[string]interface{}{"color": "#ffffff", "period": "1s", "width": 0.5, "blend": "rgb"},
+			panels:     cross,
+			elapsed:    125 * time.Millisecond,
+			want:       map[int]string{1: "#808080", 2: "#808080", 3: "#000000", 4: "#000000"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layout := make(map[int]*client.Panel)
+			for _, panel := range tt.panels {
+				layout[panel.ID] = panel
+			}
+			canvas := recordingCanvas{}
+			action, err := newAction(StatusConfigSet{Type: tt.actionType, Config: tt.config}, ActionContext{
+				Geometry: tt.panels,
+				Layout:   layout,
+				Canvas:   canvas,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			action.(*spatialAction).draw(tt.elapsed)
+			if got := canvas.hexes(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("colors = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpatialErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		actionType string
+		config     map[string]interface{}
+	}{
+		{name: "missing color", actionType: "wipe", config: map[string]interface{}{}},
+		{name: "invalid from color", actionType: "sweep", config: map[string]interface{}{"color": "#ffffff", "from": "dark"}},
+		{name: "unknown blend", actionType: "wipe", config: map[string]interface{}{"color": "#ffffff", "blend": "cmyk"}},
+		{name: "unknown center panel", actionType: "ripple", config: map[string]interface{}{"color": "#ffffff", "center": 9}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newAction(StatusConfigSet{Type: tt.actionType, Config: tt.config}, ActionContext{Canvas: recordingCanvas{}}); err == nil {
+				t.Errorf("newAction(%v) succeeded, want an error", tt.config)
+			}
+		})
+	}
+}
@@ -3,6 +3,9 @@ package auroraops
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
 	"time"
 
 	colorful "github.com/lucasb-eyer/go-colorful"
@@ -11,92 +14,302 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// StatusConfigSet describes how a status is drawn. Type names the registered
+// action type that draws it, and Config holds the rest of the status
+// configuration, which the action type decodes itself. Critical statuses are
+// drawn as configured even during quiet hours.
 type StatusConfigSet struct {
-	Color string `mapstructure:"color"`
-	Type  string `mapstructure:"type"`
+	Type       string
+	Transition TransitionConfig
+	Critical   bool
+	Config     map[string]interface{}
 }
 
+// pollerKeys are the settings of the remote configuration poller, which share
+// the status section with the statuses themselves.
+var pollerKeys = map[string]bool{
+	"location": true,
+	"interval": true,
+}
+
+// ParseStatusConfig reads the status section of the configuration, splitting
+// each status into its type, its transition, whether it is critical and the
+// configuration of its action type.
+func ParseStatusConfig(raw interface{}) (map[string]StatusConfigSet, error) {
+	statuses := map[string]interface{}{}
+	if err := decodeConfig(raw, &statuses, false); err != nil {
+		return nil, err
+	}
+	parsed := make(map[string]StatusConfigSet)
+	for status, config := range statuses {
+		if pollerKeys[status] {
+			continue
+		}
+		statusConfig, err := parseStatusConfig(config)
+		if err != nil {
+			return nil, errors.Wrapf(err, "status %s", status)
+		}
+		parsed[status] = statusConfig
+	}
+	return parsed, nil
+}
+
+func parseStatusConfig(raw interface{}) (StatusConfigSet, error) {
+	statusConfig := StatusConfigSet{
+		Config: make(map[string]interface{}),
+	}
+	config := map[string]interface{}{}
+	if err := decodeConfig(raw, &config, false); err != nil {
+		return statusConfig, err
+	}
+	for key, value := range config {
+		switch key {
+		case "type":
+			if err := decodeConfig(value, &statusConfig.Type, true); err != nil {
+				return statusConfig, err
+			}
+		case "transition":
+			if err := decodeConfig(value, &statusConfig.Transition, true); err != nil {
+				return statusConfig, err
+			}
+		case "critical":
+			if err := decodeConfig(value, &statusConfig.Critical, true); err != nil {
+				return statusConfig, err
+			}
+		default:
+			statusConfig.Config[key] = value
+		}
+	}
+	return statusConfig, nil
+}
+
+// ThingConfigSet describes a thing and the panels it draws on. Things with a
+// higher priority are overlays: while they have a status they take over their
+// panels, and when they are cleared the things below them show through again.
+// Setting All makes the thing cover every panel on the aurora. Opacity blends
+// the thing with the layers below it; unset means fully opaque. Value names the
+// status used when the thing is given a number instead of a status name.
+// Select adds panels by where they are on the wall, and is resolved against the
+// layout whenever it is read from the aurora.
 type ThingConfigSet struct {
-	Panels  []int  `mapstructure:"panels"`
-	OnStart string `mapstructure:"onstart"`
-	OnStop  string `mapstructure:"onstop"`
+	Panels   []int           `mapstructure:"panels" schema:"panel"`
+	Select   []PanelSelector `mapstructure:"select"`
+	All      bool            `mapstructure:"all"`
+	Priority int             `mapstructure:"priority"`
+	Opacity  float64         `mapstructure:"opacity"`
+	Value    string          `mapstructure:"value"`
+	OnStart  string          `mapstructure:"onstart" schema:"color"`
+	OnStop   string          `mapstructure:"onstop" schema:"color"`
+}
+
+// ParseThingConfig reads the things section of the configuration. Panels are
+// given as panel IDs of the first device or as references to panels of named
+// devices such as "hall:13".
+func ParseThingConfig(raw interface{}, devices []DeviceConfig) (map[string]ThingConfigSet, error) {
+	things := make(map[string]ThingConfigSet)
+	if raw == nil {
+		return things, nil
+	}
+	if err := decodeConfig(raw, &things, false, panelRefHookFunc(devices)); err != nil {
+		return nil, err
+	}
+	return things, nil
 }
 
 type ThingManager struct {
 	auroraClient client.AuroraClient
+	compositor   *Compositor
 	Status       map[string]StatusConfigSet
 	Things       map[string]ThingConfigSet
-	panelGroups  map[string]*panelGroup
+	// Devices are the configured auroras, which statuses can refer to panels
+	// of by name.
+	Devices     []DeviceConfig
+	panelGroups map[string]*panelGroup
+	layout      map[int]*client.Panel
+	allPanels   []int
+	remap       map[int]int
+	still       bool
+	changed     chan struct{}
+
+	mu sync.Mutex
 }
 
-func NewThingManager(auroraClient client.AuroraClient) *ThingManager {
+func NewThingManager(auroraClient client.AuroraClient, compositor *Compositor) *ThingManager {
 	return &ThingManager{
 		auroraClient: auroraClient,
+		compositor:   compositor,
 		Status:       make(map[string]StatusConfigSet),
 		Things:       make(map[string]ThingConfigSet),
 		panelGroups:  make(map[string]*panelGroup),
+		layout:       make(map[int]*client.Panel),
+		changed:      make(chan struct{}, 1),
 	}
 }
 
-func (m *ThingManager) validate() error {
-	panels := []int{}
-	for _, thingConfig := range m.Things {
+func validateConfig(status map[string]StatusConfigSet, things map[string]ThingConfigSet, devices []DeviceConfig) error {
+	for status, statusConfig := range status {
+		if err := checkStatusConfig(statusConfig, devices); err != nil {
+			return errors.Wrapf(err, "status %s", status)
+		}
+	}
+	return checkThings(things)
+}
+
+// resolveThings returns the things with the panels they draw on, resolving
+// their selectors against the layout and giving things that cover all panels
+// every panel.
+func resolveThings(things map[string]ThingConfigSet, layout []*client.Panel) (map[string]ThingConfigSet, error) {
+	resolved := make(map[string]ThingConfigSet, len(things))
+	for thing, thingConfig := range things {
+		if thingConfig.All {
+			thingConfig.Panels = []int{}
+			for _, panel := range layout {
+				thingConfig.Panels = append(thingConfig.Panels, panel.ID)
+			}
+		} else {
+			panels, err := selectPanels(thingConfig, layout)
+			if err != nil {
+				return nil, errors.Wrapf(err, "thing %s", thing)
+			}
+			thingConfig.Panels = panels
+		}
+		resolved[thing] = thingConfig
+	}
+	return resolved, nil
+}
+
+// checkThings makes sure that no two things draw on the same panel at the same
+// priority.
+func checkThings(things map[string]ThingConfigSet) error {
+	panels := map[int][]int{}
+	wholeWall := map[int]string{}
+	for thing, thingConfig := range things {
+		if thingConfig.All {
+			if other, ok := wholeWall[thingConfig.Priority]; ok {
+				return fmt.Errorf("Things %s and %s both cover all panels at priority %d.", other, thing, thingConfig.Priority)
+			}
+			wholeWall[thingConfig.Priority] = thing
+			continue
+		}
 		for _, panel := range thingConfig.Panels {
-			if containsInt(panels, panel) {
-				return fmt.Errorf("Pannel %d is referenced in multiple things.", panel)
+			if containsInt(panels[thingConfig.Priority], panel) {
+				return fmt.Errorf("Pannel %d is referenced in multiple things at priority %d.", panel, thingConfig.Priority)
 			}
-			panels = append(panels, panel)
+			panels[thingConfig.Priority] = append(panels[thingConfig.Priority], panel)
+		}
+	}
+	for priority, thing := range wholeWall {
+		if len(panels[priority]) > 0 {
+			return fmt.Errorf("Thing %s covers all panels but other things share priority %d.", thing, priority)
 		}
 	}
 	return nil
 }
 
 func (m *ThingManager) Init() error {
-	if err := m.validate(); err != nil {
+	panelInfo, err := m.auroraClient.GetInfo()
+	if err != nil {
+		return errors.Wrap(err, "could not get panel layout")
+	}
+	m.setLayout(panelInfo.Panels)
+	resolved, err := resolveThings(m.Things, m.layoutPanels())
+	if err != nil {
 		return err
 	}
-	for thing, thingInfo := range m.Things {
-		m.panelGroups[thing] = &panelGroup{
-			thing:  thing,
-			panels: thingInfo.Panels,
-			currentState: panelGroupState{
-				status:    "",
-				updatedAt: time.Now(),
-			},
-			action:  NewNoOpAction(),
-			onStart: thingInfo.OnStart,
-			onStop:  thingInfo.OnStop,
-		}
+	if err := validateConfig(m.Status, resolved, m.Devices); err != nil {
+		return err
+	}
+	for thing, thingInfo := range resolved {
+		m.panelGroups[thing] = m.newPanelGroup(thing, thingInfo)
 	}
 	return nil
 }
 
-func (m *ThingManager) StartAll(auroraClient client.AuroraClient) error {
+func (m *ThingManager) setLayout(panels []*client.Panel) {
+	m.layout = make(map[int]*client.Panel)
+	m.allPanels = []int{}
+	for _, panel := range panels {
+		m.layout[panel.ID] = panel
+		m.allPanels = append(m.allPanels, panel.ID)
+	}
+}
+
+// layoutPanels returns the panels of the layout in the order the aurora
+// reported them.
+func (m *ThingManager) layoutPanels() []*client.Panel {
+	panels := make([]*client.Panel, 0, len(m.allPanels))
+	for _, id := range m.allPanels {
+		panels = append(panels, m.layout[id])
+	}
+	return panels
+}
+
+// newPanelGroup creates the panel group of a thing whose panels are resolved.
+func (m *ThingManager) newPanelGroup(thing string, thingInfo ThingConfigSet) *panelGroup {
+	return &panelGroup{
+		thing:    thing,
+		panels:   thingInfo.Panels,
+		priority: thingInfo.Priority,
+		opacity:  thingInfo.Opacity,
+		layer:    m.compositor.newLayer(thingInfo.Priority, thingInfo.Opacity),
+		currentState: panelGroupState{
+			status:    "",
+			updatedAt: time.Now(),
+		},
+		action:  NewNoOpAction(),
+		onStart: thingInfo.OnStart,
+		onStop:  thingInfo.OnStop,
+	}
+}
+
+// geometry returns the layout of the given panels, skipping any the aurora
+// does not know about.
+func (m *ThingManager) geometry(panels []int) []*client.Panel {
+	geometry := []*client.Panel{}
+	for _, id := range panels {
+		panel, ok := m.layout[id]
+		if !ok {
+			log.WithField("panel", id).Warn("Panel is not in the layout.")
+			continue
+		}
+		geometry = append(geometry, panel)
+	}
+	return geometry
+}
+
+func (m *ThingManager) StartAll() error {
 	for _, panelGroup := range m.panelGroups {
-		if panelGroup.onStart != "" {
-			color, err := colorful.Hex(panelGroup.onStart)
-			if err != nil {
-				return err
-			}
-			action, err := NewSolidFillAction(auroraClient, panelGroup.panels, color)
-			if err != nil {
-				return err
-			}
-			panelGroup.action = action
-			err = panelGroup.action.Start()
-			if err != nil {
-				return err
-			}
+		if err := m.startPanelGroup(panelGroup); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-func (m *ThingManager) StopAll(auroraClient client.AuroraClient) error {
+func (m *ThingManager) startPanelGroup(panelGroup *panelGroup) error {
+	if panelGroup.onStart == "" {
+		return nil
+	}
+	color, err := colorful.Hex(panelGroup.onStart)
+	if err != nil {
+		return err
+	}
+	action, err := NewSolidFillAction(panelGroup.layer, panelGroup.panels, color)
+	if err != nil {
+		return err
+	}
+	panelGroup.action = action
+	return panelGroup.action.Start()
+}
+
+func (m *ThingManager) StopAll() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	for _, panelGroup := range m.panelGroups {
+		if err := panelGroup.endTransition(ctx, nil); err != nil {
+			return err
+		}
 		if err := panelGroup.action.Stop(ctx); err != nil {
 			return err
 		}
@@ -105,7 +318,7 @@ func (m *ThingManager) StopAll(auroraClient client.AuroraClient) error {
 			if err != nil {
 				return err
 			}
-			action, err := NewSolidFillAction(auroraClient, panelGroup.panels, color)
+			action, err := NewSolidFillAction(panelGroup.layer, panelGroup.panels, color)
 			if err != nil {
 				return err
 			}
@@ -119,58 +332,469 @@ func (m *ThingManager) StopAll(auroraClient client.AuroraClient) error {
 	return nil
 }
 
+// Reload replaces the status and thing configuration of a running manager. The
+// new configuration is validated first and rejected as a whole if it is
+// invalid. Things that were removed or changed are stopped and cleared, things
+// that were added or changed are started and given back their status, and
+// things whose status is drawn differently are redrawn. Every other thing
+// keeps running untouched.
+func (m *ThingManager) Reload(status map[string]StatusConfigSet, things map[string]ThingConfigSet) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.reload(status, things)
+}
+
+// RefreshLayout reads the panel layout from the aurora again and resolves the
+// panels of things that select them by geometry. Things whose panels changed
+// are restarted.
+func (m *ThingManager) RefreshLayout() error {
+	panelInfo, err := m.auroraClient.GetInfo()
+	if err != nil {
+		return errors.Wrap(err, "could not get panel layout")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.applyLayout(panelInfo.Panels, m.remap)
+}
+
+// UpdateLayout replaces the panel layout. Remap maps the panel IDs of the
+// configuration to the IDs the panels have in the new layout, and is kept for
+// configuration that is reloaded later. Things whose panels changed are
+// restarted.
+func (m *ThingManager) UpdateLayout(panels []*client.Panel, remap map[int]int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.applyLayout(panels, remap)
+}
+
+func (m *ThingManager) applyLayout(panels []*client.Panel, remap map[int]int) error {
+	previousLayout, previousRemap := m.layoutPanels(), m.remap
+	m.setLayout(panels)
+	m.remap = remap
+	if err := m.reload(m.Status, m.Things); err != nil {
+		m.setLayout(previousLayout)
+		m.remap = previousRemap
+		return err
+	}
+	return nil
+}
+
+// remapThings returns the things with their panel IDs remapped.
+func remapThings(things map[string]ThingConfigSet, remap map[int]int) map[string]ThingConfigSet {
+	if len(remap) == 0 {
+		return things
+	}
+	remapped := make(map[string]ThingConfigSet, len(things))
+	for thing, thingConfig := range things {
+		panels := make([]int, len(thingConfig.Panels))
+		for i, panel := range thingConfig.Panels {
+			panels[i] = panel
+			if to, ok := remap[panel]; ok {
+				panels[i] = to
+			}
+		}
+		thingConfig.Panels = panels
+		remapped[thing] = thingConfig
+	}
+	return remapped
+}
+
+func (m *ThingManager) reload(status map[string]StatusConfigSet, things map[string]ThingConfigSet) error {
+	resolved, err := resolveThings(remapThings(things, m.remap), m.layoutPanels())
+	if err != nil {
+		return err
+	}
+	if err := validateConfig(status, resolved, m.Devices); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	previousStatus := m.Status
+	previousThings := m.Things
+	currentStatus := make(map[string]string)
+	for thing, pg := range m.panelGroups {
+		currentStatus[thing] = pg.currentState.status
+	}
+
+	replaced := []string{}
+	for thing, pg := range m.panelGroups {
+		thingInfo, ok := resolved[thing]
+		if ok && reflect.DeepEqual(things[thing], previousThings[thing]) && reflect.DeepEqual(thingInfo.Panels, pg.panels) {
+			continue
+		}
+		if err := pg.endTransition(ctx, nil); err != nil {
+			return err
+		}
+		if err := pg.action.Stop(ctx); err != nil {
+			return err
+		}
+		pg.clearAcknowledgement()
+		m.compositor.removeLayer(pg.layer)
+		delete(m.panelGroups, thing)
+		if ok {
+			replaced = append(replaced, thing)
+			log.WithField("thing", thing).Info("Thing changed.")
+		} else {
+			log.WithField("thing", thing).Info("Thing removed.")
+		}
+	}
+
+	m.Status = status
+	m.Things = things
+
+	for thing, thingInfo := range resolved {
+		if _, ok := m.panelGroups[thing]; ok {
+			continue
+		}
+		if !containsString(replaced, thing) {
+			log.WithField("thing", thing).Info("Thing added.")
+		}
+		pg := m.newPanelGroup(thing, thingInfo)
+		m.panelGroups[thing] = pg
+		if err := m.startPanelGroup(pg); err != nil {
+			return errors.Wrapf(err, "could not start thing %s", thing)
+		}
+		if currentStatus[thing] == "" {
+			continue
+		}
+		if err := m.updateThing(thing, currentStatus[thing], false); err != nil {
+			log.WithError(err).WithField("thing", thing).Warn("Could not restore status.")
+		}
+	}
+
+	for thing, pg := range m.panelGroups {
+		if pg.currentState.status == "" || containsString(replaced, thing) {
+			continue
+		}
+		previous, _, _ := resolveStatus(previousStatus, previousThings, thing, pg.currentState.status)
+		name, _, ok := m.resolveStatus(thing, pg.currentState.status)
+		if ok && name == previous && reflect.DeepEqual(status[name], previousStatus[previous]) {
+			continue
+		}
+		log.WithField("thing", thing).Info("Status changed, redrawing thing.")
+		if !ok {
+			if err := m.clearPanelGroup(ctx, pg); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := m.updateThing(thing, pg.currentState.status, true); err != nil {
+			log.WithError(err).WithField("thing", thing).Warn("Could not redraw thing.")
+		}
+	}
+	m.notify()
+	return nil
+}
+
+// clearPanelGroup stops the action of a thing whose status no longer exists.
+func (m *ThingManager) clearPanelGroup(ctx context.Context, pg *panelGroup) error {
+	if err := pg.endTransition(ctx, nil); err != nil {
+		return err
+	}
+	if err := pg.action.Stop(ctx); err != nil {
+		return err
+	}
+	for _, panel := range pg.panels {
+		pg.layer.ClearPanel(panel)
+	}
+	pg.clearAcknowledgement()
+	pg.action = NewNoOpAction()
+	pg.currentState = panelGroupState{updatedAt: time.Now()}
+	return nil
+}
+
+// resolveStatus returns the configured status a thing's remote status refers
+// to. Things with a value status also accept numbers, which are returned with
+// that status.
+func (m *ThingManager) resolveStatus(thing, status string) (string, float64, bool) {
+	return resolveStatus(m.Status, m.Things, thing, status)
+}
+
+func resolveStatus(statuses map[string]StatusConfigSet, things map[string]ThingConfigSet, thing, status string) (string, float64, bool) {
+	if _, ok := statuses[status]; ok {
+		return status, 0, true
+	}
+	thingConfig, ok := things[thing]
+	if !ok || thingConfig.Value == "" {
+		return "", 0, false
+	}
+	if _, ok := statuses[thingConfig.Value]; !ok {
+		return "", 0, false
+	}
+	value, err := strconv.ParseFloat(status, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return thingConfig.Value, value, true
+}
+
+// HasThing returns true when the thing is configured.
+func (m *ThingManager) HasThing(thing string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.Things[thing]
+	return ok
+}
+
+// HasStatus returns true when the status is configured.
+func (m *ThingManager) HasStatus(status string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.Status[status]
+	return ok
+}
+
+// Accepts returns true when the thing is configured and can be given the
+// status.
+func (m *ThingManager) Accepts(thing, status string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.Things[thing]; !ok {
+		return false
+	}
+	_, _, ok := m.resolveStatus(thing, status)
+	return ok
+}
+
 func (m *ThingManager) UpdateThing(thing, status string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.updateThing(thing, status, false); err != nil {
+		return err
+	}
+	m.notify()
+	return nil
+}
+
+// SetStill downgrades the animated statuses of things that are not critical
+// to solid colors, or restores them, redrawing every thing that has a status.
+func (m *ThingManager) SetStill(still bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.still == still {
+		return nil
+	}
+	m.still = still
+	for thing, pg := range m.panelGroups {
+		if pg.currentState.status == "" {
+			continue
+		}
+		if err := m.updateThing(thing, pg.currentState.status, true); err != nil {
+			return errors.Wrapf(err, "could not redraw thing %s", thing)
+		}
+	}
+	return nil
+}
+
+// Acknowledge draws a calmer version of a thing's current status until the
+// status changes or, when duration is greater than zero, the duration passes.
+func (m *ThingManager) Acknowledge(thing string, duration time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pg, hasPanelGroup := m.panelGroups[thing]
+	if !hasPanelGroup {
+		return fmt.Errorf("error: no panel group for thing")
+	}
+	name, _, ok := m.resolveStatus(thing, pg.currentState.status)
+	if !ok {
+		return fmt.Errorf("error: thing has no status to acknowledge")
+	}
+	pg.clearAcknowledgement()
+	ack := &acknowledgement{status: name}
+	if duration > 0 {
+		ack.timer = time.AfterFunc(duration, func() {
+			m.expireAcknowledgement(thing, ack)
+		})
+	}
+	pg.ack = ack
+	log.WithFields(log.Fields{
+		"thing":  thing,
+		"status": name,
+		"for":    duration,
+	}).Info("Acknowledged thing.")
+	if err := m.updateThing(thing, pg.currentState.status, true); err != nil {
+		return err
+	}
+	m.notify()
+	return nil
+}
+
+// Unacknowledge draws a thing's status as configured again.
+func (m *ThingManager) Unacknowledge(thing string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pg, hasPanelGroup := m.panelGroups[thing]
+	if !hasPanelGroup {
+		return fmt.Errorf("error: no panel group for thing")
+	}
+	if pg.ack == nil {
+		return nil
+	}
+	pg.clearAcknowledgement()
+	log.WithField("thing", thing).Info("Cleared acknowledgement.")
+	if err := m.updateThing(thing, pg.currentState.status, true); err != nil {
+		return err
+	}
+	m.notify()
+	return nil
+}
+
+func (m *ThingManager) expireAcknowledgement(thing string, ack *acknowledgement) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// The thing may have been removed by a reload while the timer waited.
+	pg, ok := m.panelGroups[thing]
+	if !ok || pg.ack != ack {
+		return
+	}
+	pg.ack = nil
+	log.WithField("thing", thing).Info("Acknowledgement expired.")
+	if err := m.updateThing(thing, pg.currentState.status, true); err != nil {
+		log.WithError(err).WithField("thing", thing).Error("Could not update thing.")
+	}
+	m.notify()
+}
+
+// Critical returns true when any thing has a critical status that has not
+// been acknowledged.
+func (m *ThingManager) Critical() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for thing, pg := range m.panelGroups {
+		if pg.ack != nil {
+			continue
+		}
+		name, _, ok := m.resolveStatus(thing, pg.currentState.status)
+		if ok && m.Status[name].Critical {
+			return true
+		}
+	}
+	return false
+}
+
+// Changed is signalled after a thing's status changes.
+func (m *ThingManager) Changed() <-chan struct{} {
+	return m.changed
+}
+
+func (m *ThingManager) notify() {
+	select {
+	case m.changed <- struct{}{}:
+	default:
+	}
+}
+
+// updateThing draws a thing's status. Unless forced, a thing that already has
+// the status is left alone.
+func (m *ThingManager) updateThing(thing, status string, force bool) error {
 	pg, hasPanelGroup := m.panelGroups[thing]
 	if !hasPanelGroup {
 		return fmt.Errorf("error: no panel group for thing")
 	}
-	if pg.currentState.status == status {
+	if pg.currentState.status == status && !force {
 		log.WithFields(log.Fields{
 			"thing":  thing,
 			"status": status,
 		}).Info("Thing already has this status.")
 		return nil
 	}
+	name, value, ok := m.resolveStatus(thing, status)
+	if !ok {
+		return fmt.Errorf("no action for status: %s", status)
+	}
+	if pg.ack != nil && pg.ack.status != name {
+		log.WithField("thing", thing).Info("Status changed, clearing acknowledgement.")
+		pg.clearAcknowledgement()
+	}
+
+	// Actions driven by a value are updated in place while the status they
+	// were created for stays the same.
+	if valueAction, ok := pg.action.(ValueAction); ok && !force {
+		if current, _, _ := m.resolveStatus(thing, pg.currentState.status); current == name {
+			if err := valueAction.SetValue(value); err != nil {
+				return err
+			}
+			pg.currentState.status = status
+			pg.currentState.value = value
+			pg.currentState.updatedAt = time.Now()
+			return nil
+		}
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	if err := pg.action.Stop(ctx); err != nil {
+	if err := pg.endTransition(ctx, nil); err != nil {
 		return err
 	}
 
-	newAction, err := m.actionForStatus(status, pg.panels, m.auroraClient)
+	// The new action draws on its own layer so that the outgoing action can
+	// keep running underneath it while they transition.
+	incoming := m.compositor.newLayer(pg.priority, pg.opacity)
+	newAction, err := m.actionForStatus(name, value, pg.panels, incoming, pg.ack != nil)
 	if err != nil {
+		m.compositor.removeLayer(incoming)
 		return err
 	}
-	pg.action = newAction
-	err = pg.action.Start()
+	t, err := newTransition(m.Status[name].Transition, pg.layer, pg.action, pg.panels)
 	if err != nil {
+		m.compositor.removeLayer(incoming)
+		return err
+	}
+	if err = newAction.Start(); err != nil {
+		m.compositor.removeLayer(incoming)
+		return err
+	}
+
+	outgoing, outgoingAction := pg.layer, pg.action
+	m.compositor.removeLayer(outgoing)
+	pg.layer = incoming
+	pg.action = newAction
+	if t != nil {
+		pg.beginTransition(t)
+	} else if err := outgoingAction.Stop(ctx); err != nil {
 		return err
 	}
 	pg.currentState.status = status
+	pg.currentState.value = value
 	pg.currentState.updatedAt = time.Now()
 
 	return nil
 }
 
-func (m *ThingManager) actionForStatus(status string, panels []int, ac client.AuroraClient) (Action, error) {
+// actionForStatus creates the action for a status. Acknowledged statuses, and
+// statuses that are not critical while animations are suppressed, are drawn
+// still.
+func (m *ThingManager) actionForStatus(status string, value float64, panels []int, canvas Canvas, acknowledged bool) (Action, error) {
 	statusConfig, hasStatus := m.Status[status]
 	if !hasStatus {
 		return nil, fmt.Errorf("no action for status: %s", status)
 	}
-	if statusConfig.Type == "solid" {
-		color, err := colorful.Hex(statusConfig.Color)
-		if err != nil {
-			return nil, errors.Wrapf(err, "invalid color: %s", statusConfig.Color)
-		}
-		return NewSolidFillAction(ac, panels, color)
-	} else if statusConfig.Type == "breath" {
-		to, err := colorful.Hex(statusConfig.Color)
-		if err != nil {
-			return nil, errors.Wrapf(err, "invalid color: %s", statusConfig.Color)
-		}
-		from, err := colorful.Hex("#ffffff")
-		return NewBreathAction(ac, panels, to, from, 1)
+	if acknowledged || (m.still && !statusConfig.Critical) {
+		statusConfig = stillStatus(statusConfig)
 	}
-	return nil, fmt.Errorf("unsupported status type: %s", statusConfig.Type)
+	return newAction(statusConfig, ActionContext{
+		Status:   status,
+		Value:    value,
+		Panels:   panels,
+		Geometry: m.geometry(panels),
+		Layout:   m.layout,
+		Devices:  m.Devices,
+		Canvas:   canvas,
+		Client:   m.auroraClient,
+	})
 }
@@ -0,0 +1,327 @@
+package auroraops
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ngerakines/auroraops/client"
+)
+
+func solidStatus(color string) StatusConfigSet {
+	return StatusConfigSet{Type: "solid", Config: map[string]interface{}{"color": color}}
+}
+
+// newTestThingManager creates a manager of the things on an aurora of panels 1
+// to 3 drawing through a compositor.
+func newTestThingManager(status map[string]StatusConfigSet, things map[string]ThingConfigSet) (*ThingManager, error) {
+	c, fake := newTestCompositor()
+	fake.panels = []*client.Panel{{ID: 1}, {ID: 2, X: 100}, {ID: 3, X: 200}}
+	m := NewThingManager(fake, c)
+	m.Status = status
+	m.Things = things
+	return m, m.Init()
+}
+
+// wall returns the color of panels 1 to 3 as the compositor would send them.
+func wall(m *ThingManager) map[int][3]uint8 {
+	colors := make(map[int][3]uint8)
+	for panel := 1; panel <= 3; panel++ {
+		colors[panel] = m.compositor.output(m.compositor.compose(panel, time.Now()))
+	}
+	return colors
+}
+
+func TestThingManagerOverlays(t *testing.T) {
+	status := map[string]StatusConfigSet{
+		"up":        solidStatus("#00ff00"),
+		"deploying": solidStatus("#0000ff"),
+		"declared":  solidStatus("#ff0000"),
+		"clear":     {Type: "clear"},
+	}
+	things := map[string]ThingConfigSet{
+		"website":  {Panels: []int{1, 2}},
+		"api":      {Panels: []int{3}},
+		"deploy":   {Panels: []int{2, 3}, Priority: 1},
+		"incident": {All: true, Priority: 2},
+	}
+	m, err := newTestThingManager(status, things)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		off   = [3]uint8{0, 0, 0}
+		green = [3]uint8{0, 255, 0}
+		blue  = [3]uint8{0, 0, 255}
+		red   = [3]uint8{255, 0, 0}
+	)
+	steps := []struct {
+		name   string
+		thing  string
+		status string
+		want   map[int][3]uint8
+	}{
+		{
+			name:   "draws the base layer",
+			thing:  "website",
+			status: "up",
+			want:   map[int][3]uint8{1: green, 2: green, 3: off},
+		},
+		{
+			name:   "overlays some panels",
+			thing:  "deploy",
+			status: "deploying",
+			want:   map[int][3]uint8{1: green, 2: blue, 3: blue},
+		},
+		{
+			name:   "keeps drawing under an overlay",
+			thing:  "api",
+			status: "up",
+			want:   map[int][3]uint8{1: green, 2: blue, 3: blue},
+		},
+		{
+			name:   "overlays the whole wall",
+			thing:  "incident",
+			status: "declared",
+			want:   map[int][3]uint8{1: red, 2: red, 3: red},
+		},
+		{
+			name:   "shows the overlay below when an overlay clears",
+			thing:  "incident",
+			status: "clear",
+			want:   map[int][3]uint8{1: green, 2: blue, 3: blue},
+		},
+		{
+			name:   "shows the base layer when every overlay clears",
+			thing:  "deploy",
+			status: "clear",
+			want:   map[int][3]uint8{1: green, 2: green, 3: green},
+		},
+	}
+	for _, step := range steps {
+		if err := m.UpdateThing(step.thing, step.status); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if got := wall(m); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%s: wall = %v, want %v", step.name, got, step.want)
+		}
+	}
+}
+
+func TestCheckThings(t *testing.T) {
+	tests := []struct {
+		name    string
+		things  map[string]ThingConfigSet
+		wantErr bool
+	}{
+		{
+			name: "accepts panels shared at different priorities",
+			things: map[string]ThingConfigSet{
+				"website": {Panels: []int{1, 2}},
+				"deploy":  {Panels: []int{2}, Priority: 1},
+				"outage":  {All: true, Priority: 2},
+			},
+		},
+		{
+			name: "rejects panels shared at the same priority",
+			things: map[string]ThingConfigSet{
+				"website": {Panels: []int{1, 2}},
+				"api":     {Panels: []int{2}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "rejects two things covering all panels at the same priority",
+			things: map[string]ThingConfigSet{
+				"outage":   {All: true, Priority: 1},
+				"incident": {All: true, Priority: 1},
+			},
+			wantErr: true,
+		},
+		{
+			name: "rejects things sharing the priority of a thing covering all panels",
+			things: map[string]ThingConfigSet{
+				"outage": {All: true, Priority: 1},
+				"deploy": {Panels: []int{2}, Priority: 1},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkThings(tt.things); (err != nil) != tt.wantErr {
+				t.Errorf("checkThings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestThingManagerReload(t *testing.T) {
+	status := map[string]StatusConfigSet{
+		"up":   solidStatus("#00ff00"),
+		"down": solidStatus("#ff0000"),
+	}
+	things := map[string]ThingConfigSet{
+		"website": {Panels: []int{1}},
+		"api":     {Panels: []int{2}},
+	}
+	withStatus := func(name string, statusConfig *StatusConfigSet) map[string]StatusConfigSet {
+		changed := make(map[string]StatusConfigSet)
+		for status, config := range status {
+			changed[status] = config
+		}
+		if statusConfig == nil {
+			delete(changed, name)
+		} else {
+			changed[name] = *statusConfig
+		}
+		return changed
+	}
+	withThing := func(name string, thingConfig *ThingConfigSet) map[string]ThingConfigSet {
+		changed := make(map[string]ThingConfigSet)
+		for thing, config := range things {
+			changed[thing] = config
+		}
+		if thingConfig == nil {
+			delete(changed, name)
+		} else {
+			changed[name] = *thingConfig
+		}
+		return changed
+	}
+	blue := solidStatus("#0000ff")
+
+	var (
+		off   = [3]uint8{0, 0, 0}
+		green = [3]uint8{0, 255, 0}
+		red   = [3]uint8{255, 0, 0}
+	)
+	tests := []struct {
+		name       string
+		status     map[string]StatusConfigSet
+		things     map[string]ThingConfigSet
+		wantErr    bool
+		wantThings []string
+		wantKept   []string
+		wantWall   map[int][3]uint8
+	}{
+		{
+			name:       "keeps things that did not change running",
+			status:     status,
+			things:     things,
+			wantThings: []string{"api", "website"},
+			wantKept:   []string{"api", "website"},
+			wantWall:   map[int][3]uint8{1: green, 2: red, 3: off},
+		},
+		{
+			name:       "restarts things that changed with their status",
+			status:     status,
+			things:     withThing("api", &ThingConfigSet{Panels: []int{2, 3}}),
+			wantThings: []string{"api", "website"},
+			wantKept:   []string{"website"},
+			wantWall:   map[int][3]uint8{1: green, 2: red, 3: red},
+		},
+		{
+			name:       "clears things that were removed",
+			status:     status,
+			things:     withThing("api", nil),
+			wantThings: []string{"website"},
+			wantKept:   []string{"website"},
+			wantWall:   map[int][3]uint8{1: green, 2: off, 3: off},
+		},
+		{
+			name:       "adds things without a status",
+			status:     status,
+			things:     withThing("database", &ThingConfigSet{Panels: []int{3}}),
+			wantThings: []string{"api", "database", "website"},
+			wantKept:   []string{"api", "website"},
+			wantWall:   map[int][3]uint8{1: green, 2: red, 3: off},
+		},
+		{
+			name:       "redraws things whose status changed",
+			status:     withStatus("up", &blue),
+			things:     things,
+			wantThings: []string{"api", "website"},
+			wantKept:   []string{"api"},
+			wantWall:   map[int][3]uint8{1: {0, 0, 255}, 2: red, 3: off},
+		},
+		{
+			name:       "clears things whose status was removed",
+			status:     withStatus("down", nil),
+			things:     things,
+			wantThings: []string{"api", "website"},
+			wantKept:   []string{"website"},
+			wantWall:   map[int][3]uint8{1: green, 2: off, 3: off},
+		},
+		{
+			name:       "rejects invalid statuses",
+			status:     withStatus("down", &StatusConfigSet{Type: "sparkle"}),
+			things:     things,
+			wantErr:    true,
+			wantThings: []string{"api", "website"},
+			wantKept:   []string{"api", "website"},
+			wantWall:   map[int][3]uint8{1: green, 2: red, 3: off},
+		},
+		{
+			name:       "rejects things that share panels",
+			status:     status,
+			things:     withThing("api", &ThingConfigSet{Panels: []int{1, 2}}),
+			wantErr:    true,
+			wantThings: []string{"api", "website"},
+			wantKept:   []string{"api", "website"},
+			wantWall:   map[int][3]uint8{1: green, 2: red, 3: off},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := newTestThingManager(status, things)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := m.UpdateThing("website", "up"); err != nil {
+				t.Fatal(err)
+			}
+			if err := m.UpdateThing("api", "down"); err != nil {
+				t.Fatal(err)
+			}
+			actions := make(map[string]Action)
+			for thing, pg := range m.panelGroups {
+				actions[thing] = pg.action
+			}
+
+			if err := m.Reload(tt.status, tt.things); (err != nil) != tt.wantErr {
+				t.Fatalf("Reload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			wantConfig := tt.things
+			if tt.wantErr {
+				wantConfig = things
+			}
+			if !reflect.DeepEqual(m.Things, wantConfig) {
+				t.Errorf("Things = %v, want %v", m.Things, wantConfig)
+			}
+			got := []string{}
+			for thing := range m.panelGroups {
+				got = append(got, thing)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.wantThings) {
+				t.Errorf("things = %v, want %v", got, tt.wantThings)
+			}
+			kept := []string{}
+			for thing, pg := range m.panelGroups {
+				if pg.action == actions[thing] {
+					kept = append(kept, thing)
+				}
+			}
+			sort.Strings(kept)
+			if !reflect.DeepEqual(kept, tt.wantKept) {
+				t.Errorf("kept = %v, want %v", kept, tt.wantKept)
+			}
+			if got := wall(m); !reflect.DeepEqual(got, tt.wantWall) {
+				t.Errorf("wall = %v, want %v", got, tt.wantWall)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package auroraops
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	colorful "github.com/lucasb-eyer/go-colorful"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	tomb "gopkg.in/tomb.v2"
+)
+
+// Keyframe is the color of panels at an offset into a timeline. Color applies
+// to every panel, and is overridden by the colors of named groups and then of
+// individual panels. Panels without a color in a keyframe are interpolated
+// between the keyframes around it.
+type Keyframe struct {
+	Offset time.Duration     `mapstructure:"offset"`
+	Color  string            `mapstructure:"color" schema:"color"`
+	Groups map[string]string `mapstructure:"groups" schema:"color"`
+	Panels map[int]string    `mapstructure:"panels" schema:"color"`
+}
+
+type timelineAction struct {
+	panels   []int
+	tracks   []gradientTable
+	duration time.Duration
+	loopMode string
+	easing   Easing
+	blend    Blend
+
+	canvas Canvas
+
+	t  tomb.Tomb
+	mu sync.Mutex
+}
+
+// NewTimelineAction creates an action that plays keyframes over the duration.
+// The loop mode is "once", which holds the last frame, "loop" or "ping-pong".
+// Groups name sets of panels that keyframes can color together.
+func NewTimelineAction(canvas Canvas, panels []int, keyframes []Keyframe, groups map[string][]int, duration time.Duration, loopMode string, easing Easing, blend Blend) (Action, error) {
+	log.Info("New timeline action")
+	if duration <= 0 {
+		return nil, fmt.Errorf("error: duration must be greater than zero")
+	}
+	switch loopMode {
+	case "once", "loop", "ping-pong":
+	default:
+		return nil, fmt.Errorf("unsupported loop mode: %s", loopMode)
+	}
+	sorted := make([]Keyframe, len(keyframes))
+	copy(sorted, keyframes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Offset < sorted[j].Offset
+	})
+
+	tracks := make([]gradientTable, len(panels))
+	for _, keyframe := range sorted {
+		if keyframe.Offset < 0 || keyframe.Offset > duration {
+			return nil, fmt.Errorf("error: keyframe offset %s is outside of the timeline", keyframe.Offset)
+		}
+		colors, err := keyframe.colors(panels, groups)
+		if err != nil {
+			return nil, err
+		}
+		pos := float64(keyframe.Offset) / float64(duration)
+		for i, panel := range panels {
+			if color, ok := colors[panel]; ok {
+				tracks[i] = append(tracks[i], struct {
+					Col colorful.Color
+					Pos float64
+				}{color, pos})
+			}
+		}
+	}
+	for i, panel := range panels {
+		if len(tracks[i]) == 0 {
+			return nil, fmt.Errorf("error: no keyframe has a color for panel %d", panel)
+		}
+	}
+
+	return &timelineAction{
+		panels:   panels,
+		tracks:   tracks,
+		duration: duration,
+		loopMode: loopMode,
+		easing:   easing,
+		blend:    blend,
+		canvas:   canvas,
+	}, nil
+}
+
+// colors returns the color of each panel that the keyframe sets.
+func (k Keyframe) colors(panels []int, groups map[string][]int) (map[int]colorful.Color, error) {
+	colors := make(map[int]colorful.Color)
+	if k.Color != "" {
+		color, err := colorful.Hex(k.Color)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid color: %s", k.Color)
+		}
+		for _, panel := range panels {
+			colors[panel] = color
+		}
+	}
+	for group, hex := range k.Groups {
+		members, ok := groups[group]
+		if !ok {
+			return nil, fmt.Errorf("unknown group: %s", group)
+		}
+		color, err := colorful.Hex(hex)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid color: %s", hex)
+		}
+		for _, panel := range members {
+			colors[panel] = color
+		}
+	}
+	for panel, hex := range k.Panels {
+		color, err := colorful.Hex(hex)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid color: %s", hex)
+		}
+		colors[panel] = color
+	}
+	return colors, nil
+}
+
+// position returns how far into the timeline it is after elapsed time, and
+// whether the timeline has finished.
+func (a *timelineAction) position(elapsed time.Duration) (float64, bool) {
+	switch a.loopMode {
+	case "once":
+		if elapsed >= a.duration {
+			return 1, true
+		}
+	case "ping-pong":
+		cycle := elapsed % (2 * a.duration)
+		if cycle > a.duration {
+			cycle = 2*a.duration - cycle
+		}
+		return float64(cycle) / float64(a.duration), false
+	}
+	return float64(elapsed%a.duration) / float64(a.duration), false
+}
+
+func (a *timelineAction) draw(pos float64) {
+	for i, panel := range a.panels {
+		track := a.tracks[i]
+		color := track[0].Col
+		if pos > track[len(track)-1].Pos {
+			color = track[len(track)-1].Col
+		} else if pos >= track[0].Pos {
+			color = track.interpolate(pos, a.easing, a.blend)
+		}
+		a.canvas.SetPanelColor(panel, color)
+	}
+}
+
+func (a *timelineAction) loop() error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	start := time.Now()
+	a.draw(0)
+	for {
+		select {
+		case t := <-ticker.C:
+			pos, done := a.position(t.Sub(start))
+			a.draw(pos)
+			if done {
+				return nil
+			}
+		case <-a.t.Dying():
+			return nil
+		}
+	}
+}
+
+func (a *timelineAction) Start() error {
+	a.t.Go(a.loop)
+	return nil
+}
+
+func (a *timelineAction) Stop(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	log.WithField("action", "timeline").Info("Stopping")
+	a.t.Kill(nil)
+	return a.t.Wait()
+}
+
+// timelineConfig loops unless Loop is "once" or "ping-pong".
+type timelineConfig struct {
+	Duration  time.Duration    `mapstructure:"duration"`
+	Loop      string           `mapstructure:"loop"`
+	Keyframes []Keyframe       `mapstructure:"keyframes"`
+	Groups    map[string][]int `mapstructure:"groups" schema:"panel"`
+	Easing    string           `mapstructure:"easing"`
+	Blend     string           `mapstructure:"blend"`
+}
+
+func init() {
+	RegisterAction("timeline", newTimelineFromConfig, timelineConfig{})
+}
+
+func newTimelineFromConfig(config map[string]interface{}, ac ActionContext) (Action, error) {
+	tc := timelineConfig{}
+	if err := ac.DecodeConfig(config, &tc); err != nil {
+		return nil, err
+	}
+	loopMode := tc.Loop
+	if loopMode == "" {
+		loopMode = "loop"
+	}
+	easing, err := EasingFor(tc.Easing)
+	if err != nil {
+		return nil, err
+	}
+	blend, err := BlendFor(tc.Blend)
+	if err != nil {
+		return nil, err
+	}
+	return NewTimelineAction(ac.Canvas, ac.Panels, tc.Keyframes, tc.Groups, tc.Duration, loopMode, easing, blend)
+}
@@ -0,0 +1,142 @@
+package auroraops
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTimelineAction(t *testing.T) {
+	fade := []interface{}{
+		map[string]interface{}{"offset": "0s", "color": "#000000"},
+		map[string]interface{}{"offset": "2s", "color": "#ffffff"},
+	}
+	tests := []struct {
+		name     string
+		config   map[string]interface{}
+		elapsed  time.Duration
+		want     map[int]string
+		wantDone bool
+	}{
+		{
+			name:    "interpolates between keyframes",
+			config:  map[string]interface{}{"duration": "2s", "keyframes": fade, "blend": "rgb"},
+			elapsed: time.Second,
+			want:    map[int]string{1: "#808080", 2: "#808080"},
+		},
+		{
+			name:    "eases between keyframes",
+			config:  map[string]interface{}{"duration": "2s", "keyframes": fade, "blend": "rgb", "easing": "ease-in"},
+			elapsed: time.Second,
+			want:    map[int]string{1: "#404040", 2: "#404040"},
+		},
+		{
+			name:    "loops by default",
+			config:  map[string]interface{}{"duration": "2s", "keyframes": fade, "blend": "rgb"},
+			elapsed: 2500 * time.Millisecond,
+			want:    map[int]string{1: "#404040", 2: "#404040"},
+		},
+		{
+			name:     "holds the last frame once played",
+			config:   map[string]interface{}{"duration": "2s", "keyframes": fade, "blend": "rgb", "loop": "once"},
+			elapsed:  3 * time.Second,
+			want:     map[int]string{1: "#ffffff", 2: "#ffffff"},
+			wantDone: true,
+		},
+		{
+			name:    "plays backwards after the end in ping-pong",
+			config:  map[string]interface{}{"duration": "2s", "keyframes": fade, "blend": "rgb", "loop": "ping-pong"},
+			elapsed: 2500 * time.Millisecond,
+			want:    map[int]string{1: "#bfbfbf", 2: "#bfbfbf"},
+		},
+		{
+			name:    "plays forwards again after ping-pong",
+			config:  map[string]interface{}{"duration": "2s", "keyframes": fade, "blend": "rgb", "loop": "ping-pong"},
+			elapsed: 4500 * time.Millisecond,
+			want:    map[int]string{1: "#404040", 2: "#404040"},
+		},
+		{
+			name: "holds the first keyframe until it is reached",
+			config: map[string]interface{}{
+				"duration": "2s",
+				"keyframes": []interface{}{
+					map[string]interface{}{"offset": "1s", "color": "#ffffff"},
+					map[string]interface{}{"offset": "2s", "color": "#000000"},
+				},
+			},
+			elapsed: 500 * time.Millisecond,
+			want:    map[int]string{1: "#ffffff", 2: "#ffffff"},
+		},
+		{
+			name: "colors groups and panels on their own tracks",
+			config: map[string]interface{}{
+				"duration": "2s",
+				"groups":   map[string]interface{}{"left": []interface{}{1}},
+				"keyframes": []interface{}{
+					map[string]interface{}{"offset": "0s", "color": "#000000"},
+					map[string]interface{}{"offset": "1s", "groups": map[string]interface{}{"left": "#ffffff"}},
+					map[string]interface{}{"offset": "2s", "panels": map[string]interface{}{"2": "#ff0000"}},
+				},
+				"blend": "rgb",
+			},
+			elapsed: 1500 * time.Millisecond,
+			want:    map[int]string{1: "#ffffff", 2: "#bf0000"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			canvas := recordingCanvas{}
+			action, err := newAction(StatusConfigSet{Type: "timeline", Config: tt.config}, ActionContext{
+				Panels: []int{1, 2},
+				Canvas: canvas,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			timeline := action.(*timelineAction)
+			pos, done := timeline.position(tt.elapsed)
+			timeline.draw(pos)
+			if got := canvas.hexes(); !reflect.DeepEqual(got, tt.want) || done != tt.wantDone {
+				t.Errorf("colors = %v, done %v, want %v, done %v", got, done, tt.want, tt.wantDone)
+			}
+		})
+	}
+}
+
+func TestTimelineErrors(t *testing.T) {
+	first := map[string]interface{}{"offset": "0s", "color": "#000000"}
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+	}{
+		{name: "missing duration", config: map[string]interface{}{"keyframes": []interface{}{first}}},
+		{name: "unknown loop mode", config: map[string]interface{}{"duration": "1s", "loop": "bounce", "keyframes": []interface{}{first}}},
+		{
+			name: "keyframe after the end",
+			config: map[string]interface{}{"duration": "1s", "keyframes": []interface{}{
+				first,
+				map[string]interface{}{"offset": "2s", "color": "#ffffff"},
+			}},
+		},
+		{
+			name: "unknown group",
+			config: map[string]interface{}{"duration": "1s", "keyframes": []interface{}{
+				first,
+				map[string]interface{}{"offset": "1s", "groups": map[string]interface{}{"top": "#ffffff"}},
+			}},
+		},
+		{
+			name: "panel without a color",
+			config: map[string]interface{}{"duration": "1s", "keyframes": []interface{}{
+				map[string]interface{}{"offset": "0s", "panels": map[string]interface{}{"1": "#ffffff"}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newAction(StatusConfigSet{Type: "timeline", Config: tt.config}, ActionContext{Panels: []int{1, 2}, Canvas: recordingCanvas{}}); err == nil {
+				t.Errorf("newAction(%v) succeeded, want an error", tt.config)
+			}
+		})
+	}
+}
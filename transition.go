@@ -0,0 +1,90 @@
+package auroraops
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	colorful "github.com/lucasb-eyer/go-colorful"
+	"github.com/pkg/errors"
+)
+
+// TransitionConfig describes how a status takes over from the status it
+// replaces. Type is one of "cut" (the default), "crossfade", "wipe" or
+// "flash". Color is only used by flash transitions and defaults to white.
+type TransitionConfig struct {
+	Type     string        `mapstructure:"type"`
+	Duration time.Duration `mapstructure:"duration"`
+	Color    string        `mapstructure:"color" schema:"color"`
+}
+
+// transition mixes the output of an outgoing layer with the layer that
+// replaces it. While a transition is running the outgoing action keeps
+// drawing, so both sides stay animated.
+type transition struct {
+	kind     string
+	from     *layer
+	action   Action
+	order    map[int]int
+	flash    colorful.Color
+	start    time.Time
+	duration time.Duration
+}
+
+func newTransition(config TransitionConfig, from *layer, action Action, panels []int) (*transition, error) {
+	switch config.Type {
+	case "", "cut":
+		return nil, nil
+	case "crossfade", "wipe", "flash":
+	default:
+		return nil, fmt.Errorf("unsupported transition type: %s", config.Type)
+	}
+	if config.Duration <= 0 {
+		return nil, nil
+	}
+	flash := colorful.Color{R: 1, G: 1, B: 1}
+	if config.Color != "" {
+		var err error
+		if flash, err = colorful.Hex(config.Color); err != nil {
+			return nil, errors.Wrapf(err, "invalid color: %s", config.Color)
+		}
+	}
+	order := make(map[int]int)
+	for i, panel := range panels {
+		order[panel] = i
+	}
+	return &transition{
+		kind:     config.Type,
+		from:     from,
+		action:   action,
+		order:    order,
+		flash:    flash,
+		start:    time.Now(),
+		duration: config.Duration,
+	}, nil
+}
+
+func (t *transition) progress(now time.Time) float64 {
+	return math.Max(0, math.Min(1, float64(now.Sub(t.start))/float64(t.duration)))
+}
+
+// mix blends the outgoing and incoming colors of a panel at progress p.
+func (t *transition) mix(panel int, outgoing, incoming colorful.Color, p float64) colorful.Color {
+	switch t.kind {
+	case "wipe":
+		// Panels switch one after the other in the order they are configured,
+		// each fading over its own slice of the transition.
+		n := float64(len(t.order))
+		if n == 0 {
+			return incoming
+		}
+		local := math.Max(0, math.Min(1, p*n-float64(t.order[panel])))
+		return outgoing.BlendRgb(incoming, local)
+	case "flash":
+		if p < 0.25 {
+			return outgoing.BlendRgb(t.flash, p/0.25)
+		}
+		return t.flash.BlendRgb(incoming, (p-0.25)/0.75)
+	}
+	return outgoing.BlendRgb(incoming, p)
+}
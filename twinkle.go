@@ -0,0 +1,156 @@
+package auroraops
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	colorful "github.com/lucasb-eyer/go-colorful"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	tomb "gopkg.in/tomb.v2"
+)
+
+type twinkleAction struct {
+	panels   []int
+	from     colorful.Color
+	to       colorful.Color
+	density  float64
+	period   time.Duration
+	blend    Blend
+	progress []float64
+	rng      *rand.Rand
+
+	canvas Canvas
+
+	t  tomb.Tomb
+	mu sync.Mutex
+}
+
+// NewTwinkleAction creates an action that rests panels on one color and
+// randomly brightens them to another. Density is the chance of a resting panel
+// starting to twinkle each second, and a twinkle rises and falls over the
+// period. The same seed always produces the same twinkles.
+func NewTwinkleAction(canvas Canvas, panels []int, from, to colorful.Color, density float64, period time.Duration, blend Blend, seed int64) (Action, error) {
+	log.Info("New twinkle action")
+	if !from.IsValid() || !to.IsValid() {
+		return nil, fmt.Errorf("error: invalid color")
+	}
+	if period <= 0 {
+		return nil, fmt.Errorf("error: period must be greater than zero")
+	}
+	progress := make([]float64, len(panels))
+	for i := range progress {
+		progress[i] = -1
+	}
+	return &twinkleAction{
+		panels:   panels,
+		from:     from,
+		to:       to,
+		density:  density,
+		period:   period,
+		blend:    blend,
+		progress: progress,
+		rng:      rand.New(rand.NewSource(seed)),
+		canvas:   canvas,
+	}, nil
+}
+
+// step advances every panel by one tick of the given length and draws them.
+func (a *twinkleAction) step(tick time.Duration) {
+	chance := a.density * tick.Seconds()
+	advance := float64(tick) / float64(a.period)
+	for i, panel := range a.panels {
+		if a.progress[i] < 0 && a.rng.Float64() < chance {
+			a.progress[i] = 0
+		}
+		intensity := 0.0
+		if a.progress[i] >= 0 {
+			intensity = math.Sin(math.Pi * a.progress[i])
+			a.progress[i] += advance
+			if a.progress[i] >= 1 {
+				a.progress[i] = -1
+			}
+		}
+		a.canvas.SetPanelColor(panel, a.blend(a.from, a.to, intensity))
+	}
+}
+
+func (a *twinkleAction) loop() error {
+	tick := 50 * time.Millisecond
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			a.step(tick)
+		case <-a.t.Dying():
+			return nil
+		}
+	}
+}
+
+func (a *twinkleAction) Start() error {
+	a.t.Go(a.loop)
+	return nil
+}
+
+func (a *twinkleAction) Stop(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	log.WithField("action", "twinkle").Info("Stopping")
+	a.t.Kill(nil)
+	return a.t.Wait()
+}
+
+// twinkleConfig defaults to one second twinkles from black, with a one in ten
+// chance of each panel starting one every second. An unset Seed is picked at
+// random.
+type twinkleConfig struct {
+	Color   string        `mapstructure:"color" schema:"color"`
+	From    string        `mapstructure:"from" schema:"color"`
+	Density float64       `mapstructure:"density"`
+	Period  time.Duration `mapstructure:"period"`
+	Blend   string        `mapstructure:"blend"`
+	Seed    int64         `mapstructure:"seed"`
+}
+
+func init() {
+	RegisterAction("twinkle", newTwinkleFromConfig, twinkleConfig{})
+}
+
+func newTwinkleFromConfig(config map[string]interface{}, ac ActionContext) (Action, error) {
+	tc := twinkleConfig{}
+	if err := ac.DecodeConfig(config, &tc); err != nil {
+		return nil, err
+	}
+	to, err := colorful.Hex(tc.Color)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid color: %s", tc.Color)
+	}
+	from, err := colorOrDefault(tc.From, colorful.Color{})
+	if err != nil {
+		return nil, err
+	}
+	density := tc.Density
+	if density <= 0 {
+		density = 0.1
+	}
+	period := tc.Period
+	if period <= 0 {
+		period = time.Second
+	}
+	blend, err := BlendFor(tc.Blend)
+	if err != nil {
+		return nil, err
+	}
+	seed := tc.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return NewTwinkleAction(ac.Canvas, ac.Panels, from, to, density, period, blend, seed)
+}
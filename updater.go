@@ -104,22 +104,20 @@ func (p *updater) validate(statusData StatusMap) ([]thingStatusPair, error) {
 	}
 	if warnOnUnknownThing {
 		for _, thing := range things {
-			if _, ok := p.thingManager.Things[thing]; !ok {
+			if !p.thingManager.HasThing(thing) {
 				log.WithField("thing", thing).Warn("Unexexpected thing found.")
 			}
 		}
 	}
 	if warnOnUnknownStatus {
 		for _, status := range statuses {
-			if _, ok := p.thingManager.Status[status]; !ok {
+			if !p.thingManager.HasStatus(status) && !p.isValue(statusData, status) {
 				log.WithField("status", status).Warn("Unexexpected status found.")
 			}
 		}
 	}
 	for thing, status := range statusData {
-		_, thingOK := p.thingManager.Things[thing]
-		_, statusOK := p.thingManager.Status[status]
-		if thingOK && statusOK {
+		if p.thingManager.Accepts(thing, status) {
 			pairs = append(pairs, thingStatusPair{thing, status})
 		}
 	}
@@ -127,6 +125,20 @@ func (p *updater) validate(statusData StatusMap) ([]thingStatusPair, error) {
 	return pairs, nil
 }
 
+// isValue returns true when a status is a number given to a thing that accepts
+// numbers.
+func (p *updater) isValue(statusData StatusMap, status string) bool {
+	for thing, thingStatus := range statusData {
+		if thingStatus != status {
+			continue
+		}
+		if p.thingManager.Accepts(thing, status) {
+			return true
+		}
+	}
+	return false
+}
+
 func containsString(s []string, e string) bool {
 	for _, a := range s {
 		if a == e {